@@ -0,0 +1,231 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// Default batas total byte isi archive (bisa di-override via MAX_ARCHIVE_SIZE)
+	defaultMaxArchiveSize = 1 << 30
+	// Batas jumlah file dalam satu archive
+	maxArchiveFiles = 1000
+)
+
+var (
+	errArchiveTooLarge     = errors.New("archive exceeds maximum total size")
+	errArchiveTooManyFiles = errors.New("archive exceeds maximum number of files")
+)
+
+// Handler POST /archive, ZIP dibangun on the fly dari storage reader dan
+// langsung ditulis ke response. Tidak ada yang disimpan di disk atau memory.
+func archiveHandler(c *gin.Context) {
+	var req types.ArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (len(req.Paths) == 0) == (req.Prefix == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of paths or prefix is required"})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	maxSize := envInt64("MAX_ARCHIVE_SIZE", defaultMaxArchiveSize)
+
+	// Kumpulkan attrs dulu, supaya batas ukuran dicek sebelum response dimulai
+	var objects []*cloudStorage.ObjectAttrs
+	if req.Prefix != "" {
+		objects, err = archiveObjectsByPrefix(c, bucket, req.Prefix)
+	} else {
+		objects, err = archiveObjectsByPaths(c, bucket, req.Paths)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidObjectPath):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, errArchiveTooManyFiles):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, errNotScanned), errors.Is(err, errQuarantined):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	var total int64
+	for _, attrs := range objects {
+		total += attrs.Size
+	}
+	if total > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errArchiveTooLarge.Error()})
+		return
+	}
+
+	archiveName := req.Name
+	if archiveName == "" {
+		archiveName = "archive.zip"
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", utils.ContentDisposition("attachment", path.Base(archiveName)))
+	c.Status(http.StatusOK)
+
+	// Status sudah terkirim, kalau gagal di tengah jalan central directory tidak
+	// ditulis sehingga client menerima ZIP yang rusak, bukan ZIP yang terpotong diam-diam
+	if err := writeArchive(c, c.Writer, bucket, objects, req.Prefix, maxSize); err != nil {
+		log.Printf("Failed to stream archive: %s", err)
+	}
+}
+
+func archiveObjectsByPaths(ctx context.Context, bucket *cloudStorage.BucketHandle, paths []string) ([]*cloudStorage.ObjectAttrs, error) {
+	if len(paths) > maxArchiveFiles {
+		return nil, errArchiveTooManyFiles
+	}
+
+	objects := make([]*cloudStorage.ObjectAttrs, 0, len(paths))
+	for _, p := range paths {
+		objectPath, err := utils.NormalizeObjectPath(p)
+		if err != nil {
+			return nil, err
+		}
+		// Sama seperti download biasa, file karantina tidak boleh keluar sebelum direview
+		if strings.HasPrefix(objectPath, quarantinePrefix) {
+			return nil, fmt.Errorf("%s: %w", objectPath, errQuarantined)
+		}
+
+		attrs, err := bucket.Object(objectPath).Attrs(ctx)
+		if err == nil {
+			err = checkScanned(attrs.Metadata)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", objectPath, err)
+		}
+		objects = append(objects, attrs)
+	}
+	return objects, nil
+}
+
+func archiveObjectsByPrefix(ctx context.Context, bucket *cloudStorage.BucketHandle, prefix string) ([]*cloudStorage.ObjectAttrs, error) {
+	if _, err := utils.NormalizeObjectPath(strings.TrimSuffix(prefix, "/")); err != nil {
+		return nil, err
+	}
+
+	var objects []*cloudStorage.ObjectAttrs
+	it := bucket.Objects(ctx, &cloudStorage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Object internal (chunk upload, blob, karantina, thumbnail dan cache /img)
+		// dan file yang belum lolos scan tidak ikut di-zip
+		if isReservedPath(attrs.Name) || checkScanned(attrs.Metadata) != nil {
+			continue
+		}
+		if len(objects) >= maxArchiveFiles {
+			return nil, errArchiveTooManyFiles
+		}
+		objects = append(objects, attrs)
+	}
+	return objects, nil
+}
+
+func writeArchive(ctx context.Context, w io.Writer, bucket *cloudStorage.BucketHandle, objects []*cloudStorage.ObjectAttrs, prefix string, maxSize int64) error {
+	zw := zip.NewWriter(w)
+	names := map[string]int{}
+	var written int64
+
+	for _, attrs := range objects {
+		header := &zip.FileHeader{
+			Name:     uniqueArchiveName(names, archiveEntryName(attrs, prefix)),
+			Method:   archiveMethod(attrs.ContentType),
+			Modified: attrs.Updated,
+		}
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		// Pin ke generation yang ukurannya sudah dihitung
+		r, err := bucket.Object(attrs.Name).Generation(attrs.Generation).NewReader(ctx)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(entry, io.LimitReader(r, maxSize-written+1))
+		r.Close()
+		if err != nil {
+			return err
+		}
+
+		written += n
+		if written > maxSize {
+			return errArchiveTooLarge
+		}
+	}
+
+	return zw.Close()
+}
+
+// Nama file di dalam ZIP diambil dari metadata original_name kalau ada,
+// kalau tidak dari path relatif terhadap prefix (atau nama terakhir path)
+func archiveEntryName(attrs *cloudStorage.ObjectAttrs, prefix string) string {
+	name := path.Base(attrs.Name)
+	if prefix != "" {
+		name = strings.TrimPrefix(attrs.Name, prefix)
+		name = strings.TrimPrefix(name, "/")
+	}
+
+	if original := attrs.Metadata[originalNameMetadataKey]; original != "" {
+		name = path.Join(path.Dir(name), path.Base(original))
+	}
+
+	// Clean terhadap root supaya entry tidak bisa keluar dari folder ekstrak (zip slip)
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "file"
+	}
+	return name
+}
+
+// Hindari nama duplikat, "a.pdf" kedua jadi "a (2).pdf"
+func uniqueArchiveName(seen map[string]int, name string) string {
+	seen[name]++
+	if seen[name] == 1 {
+		return name
+	}
+
+	ext := path.Ext(name)
+	candidate := fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), seen[name], ext)
+	return uniqueArchiveName(seen, candidate)
+}
+
+// File yang sudah terkompresi (gambar, video, zip) cukup di-store
+func archiveMethod(contentType string) uint16 {
+	if strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/") || contentType == "application/zip" {
+		return zip.Store
+	}
+	return zip.Deflate
+}
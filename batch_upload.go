@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Default jumlah worker upload paralel (bisa di-override via BATCH_UPLOAD_WORKERS)
+	defaultBatchWorkers = 4
+	// Default batas jumlah file per batch (bisa di-override via MAX_BATCH_ITEMS)
+	defaultMaxBatchItems = 20
+)
+
+// Seluruh batch di-buffer di memory, jadi total body dibatasi MAX_BATCH_SIZE
+// (byte body JSON), default sama dengan batas body satu file di /upload
+var errBatchTooLarge = errors.New("batch exceeds maximum total size")
+
+// Status per item batch
+const (
+	batchStatusOK          = "ok"
+	batchStatusQuarantined = "quarantined"
+	batchStatusFailed      = "failed"
+	batchStatusSkipped     = "skipped"
+	batchStatusRolledBack  = "rolled_back"
+)
+
+type batchItemError struct {
+	Code    string              `json:"code"`
+	Status  int                 `json:"status"`
+	Message string              `json:"message"`
+	Details *utils.TypeMismatch `json:"details,omitempty"`
+}
+
+type batchItemResult struct {
+	Index        int                   `json:"index"`
+	FileName     string                `json:"file_name,omitempty"`
+	Status       string                `json:"status"`
+	ObjectName   string                `json:"object_name,omitempty"`
+	SignedURL    string                `json:"signed_url,omitempty"`
+	RawURL       string                `json:"raw_url,omitempty"`
+	FileID       string                `json:"file_id,omitempty"`
+	SHA256       string                `json:"sha256,omitempty"`
+	Deduplicated bool                  `json:"deduplicated,omitempty"`
+	Thumbnails   map[string]string     `json:"thumbnails,omitempty"`
+	Warnings     []*utils.TypeMismatch `json:"warnings,omitempty"`
+	Error        *batchItemError       `json:"error,omitempty"`
+}
+
+func newBatchItemError(err error) *batchItemError {
+	status, code := uploadErrorStatus(err)
+	e := &batchItemError{Code: code, Status: status, Message: err.Error()}
+
+	var mismatch *utils.TypeMismatch
+	if errors.As(err, &mismatch) {
+		e.Message = mismatch.Message
+		e.Details = mismatch
+	}
+	return e
+}
+
+// Handler POST /upload-batch, body berupa array UploadRequest.
+// File di-upload paralel dengan worker pool terbatas. Dengan ?atomic=true
+// (default dari BATCH_UPLOAD_ATOMIC) satu item gagal membatalkan sisanya dan
+// object yang sudah tertulis dihapus lagi.
+func uploadBatchHandler(c *gin.Context) {
+	var reqs []types.UploadRequest
+	limit := envInt64("MAX_BATCH_SIZE", base64BodyLimit(envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize)))
+	if !bindLimitedJSON(c, &reqs, limit, errBatchTooLarge) {
+		return
+	}
+	if len(reqs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one file is required"})
+		return
+	}
+	if maxItems := envInt64("MAX_BATCH_ITEMS", defaultMaxBatchItems); int64(len(reqs)) > maxItems {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "batch exceeds maximum number of files", "max_items": maxItems})
+		return
+	}
+
+	prefix, err := uploadPrefix(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	atomic := envBool("BATCH_UPLOAD_ATOMIC", false)
+	if v := c.Query("atomic"); v != "" {
+		if atomic, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid atomic parameter"})
+			return
+		}
+	}
+
+	results, failed := runBatchUpload(c.Request.Context(), reqs, prefix, uploadTenant(c), atomic)
+
+	if failed && atomic {
+		rollbackBatch(c, results)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"atomic": true, "results": results})
+		return
+	}
+
+	// URL baru dibuat setelah semua selesai, supaya tidak ada URL untuk object yang di-rollback
+	for _, result := range results {
+		if result.Status != batchStatusOK {
+			continue
+		}
+		result.SignedURL, result.RawURL, err = GenerateURL(result.ObjectName, 30, client)
+		if err != nil {
+			result.Error = newBatchItemError(err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"atomic": atomic, "results": results})
+}
+
+// Jalankan upload semua item dengan worker pool. Mengembalikan hasil per item
+// (urutan sama dengan request) dan apakah ada item yang gagal.
+func runBatchUpload(ctx context.Context, reqs []types.UploadRequest, prefix string, tenant string, atomic bool) ([]*batchItemResult, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*batchItemResult, len(reqs))
+	jobs := make(chan int)
+
+	workers := int(envInt64("BATCH_UPLOAD_WORKERS", defaultBatchWorkers))
+	if workers > len(reqs) {
+		workers = len(reqs)
+	}
+
+	var mu sync.Mutex
+	failed := false
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = uploadBatchItem(ctx, i, reqs[i], prefix, tenant, atomic)
+				if results[i].Status == batchStatusFailed {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+					if atomic {
+						cancel()
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range reqs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, failed
+}
+
+func uploadBatchItem(ctx context.Context, index int, req types.UploadRequest, prefix string, tenant string, atomic bool) *batchItemResult {
+	result := &batchItemResult{Index: index, FileName: req.FileName}
+
+	// Batch atomic sudah dibatalkan item lain, tidak perlu dikerjakan
+	if atomic && ctx.Err() != nil {
+		result.Status = batchStatusSkipped
+		return result
+	}
+
+	stored, err := storeBase64Upload(ctx, req, prefix, tenant, "UPLOAD_BATCH")
+	if err != nil {
+		if atomic && ctx.Err() != nil {
+			result.Status = batchStatusSkipped
+			return result
+		}
+		result.Status = batchStatusFailed
+		result.Error = newBatchItemError(err)
+		return result
+	}
+
+	result.ObjectName = stored.ObjectName
+	result.Status = batchStatusOK
+	if stored.Quarantined {
+		result.Status = batchStatusQuarantined
+	}
+	if stored.Mismatch != nil {
+		result.Warnings = []*utils.TypeMismatch{stored.Mismatch}
+	}
+	result.Thumbnails = stored.Thumbnails
+	if stored.Blob != nil {
+		result.FileID = stored.Blob.FileID
+		result.SHA256 = stored.Blob.SHA256
+		result.Deduplicated = stored.Blob.Deduplicated
+	}
+	return result
+}
+
+// Hapus semua object yang sudah tertulis di batch yang gagal. Blob dedupe
+// tidak dihapus langsung, cukup referensinya dilepas karena bisa saja dipakai
+// file lain.
+func rollbackBatch(ctx context.Context, results []*batchItemResult) {
+	bucket, bucketErr := client.DefaultBucket()
+
+	for _, result := range results {
+		if result.Status != batchStatusOK && result.Status != batchStatusQuarantined {
+			continue
+		}
+
+		err := bucketErr
+		switch {
+		case err != nil:
+		case result.FileID != "":
+			_, err = releaseBlobRef(ctx, client, firestoreClient, result.SHA256, result.FileID)
+		default:
+			err = bucket.Object(result.ObjectName).Delete(ctx)
+			if err == nil {
+				deleteThumbnails(ctx, bucket, result.ObjectName)
+			}
+		}
+		if err != nil {
+			// Object masih ada, nama tetap dikembalikan supaya bisa dibersihkan manual
+			result.Error = newBatchItemError(err)
+			continue
+		}
+
+		result.Status = batchStatusRolledBack
+		result.ObjectName = ""
+		result.FileID = ""
+		result.SHA256 = ""
+		result.Deduplicated = false
+		result.Thumbnails = nil
+		result.Warnings = nil
+	}
+}
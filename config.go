@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"firebase-poc/utils"
+)
+
+// Baca env var sebagai int64, fallback ke def kalau kosong atau tidak valid
+func envInt64(key string, def int64) int64 {
+	v, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+// Baca env var sebagai bool, fallback ke def kalau kosong atau tidak valid
+func envBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// Allowlist mime type per route dari env ALLOWED_TYPES_<ROUTE> (mis.
+// ALLOWED_TYPES_UPLOAD=image/*,application/pdf), fallback ke ALLOWED_TYPES.
+// Kosong berarti semua format yang dikenali utils diizinkan.
+func allowedTypes(route string) utils.Allowlist {
+	if v := os.Getenv("ALLOWED_TYPES_" + route); v != "" {
+		return utils.ParseAllowlist(v)
+	}
+	return utils.ParseAllowlist(os.Getenv("ALLOWED_TYPES"))
+}
+
+// Policy mismatch tipe file per route dari env TYPE_MISMATCH_POLICY_<ROUTE>,
+// fallback ke TYPE_MISMATCH_POLICY, default reject
+func mismatchPolicy(route string) utils.MismatchPolicy {
+	for _, key := range []string{"TYPE_MISMATCH_POLICY_" + route, "TYPE_MISMATCH_POLICY"} {
+		if policy, err := utils.ParseMismatchPolicy(os.Getenv(key)); err == nil {
+			return policy
+		}
+	}
+	return utils.PolicyReject
+}
+
+// Policy mismatch untuk ingest URL. Nama file di URL dan Content-Type dari
+// server remote bukan deklarasi client, jadi hanya dicek kalau
+// TYPE_MISMATCH_POLICY_INGEST diisi eksplisit. Selain itu hasil sniffing
+// dipakai apa adanya.
+func ingestMismatchPolicy() (utils.MismatchPolicy, bool) {
+	policy, err := utils.ParseMismatchPolicy(os.Getenv("TYPE_MISMATCH_POLICY_INGEST"))
+	if err != nil {
+		return utils.PolicyTrustSniffed, false
+	}
+	return policy, true
+}
+
+// Mode dedupe content-addressed per route dari env DEDUPE_MODE_<ROUTE>,
+// fallback ke DEDUPE_MODE, default mati
+func dedupeEnabled(route string) bool {
+	return envBool("DEDUPE_MODE_"+route, envBool("DEDUPE_MODE", false))
+}
+
+// Ukuran thumbnail (sisi terpanjang, px) per route dari env
+// THUMBNAIL_SIZES_<ROUTE>, fallback ke THUMBNAIL_SIZES, default 128,512,1024.
+// Isi "none" untuk mematikan thumbnail.
+func thumbnailSizes(route string) []int {
+	v := os.Getenv("THUMBNAIL_SIZES_" + route)
+	if v == "" {
+		v = os.Getenv("THUMBNAIL_SIZES")
+	}
+	if v == "" {
+		v = "128,512,1024"
+	}
+
+	var sizes []int
+	for _, s := range strings.Split(v, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(s))
+		if err == nil && size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes
+}
+
+// Konfigurasi penamaan object baru dari env OBJECT_NAME_SCHEME (random, ulid,
+// uuidv7), OBJECT_NAME_LENGTH (khusus random) dan OBJECT_NAME_TEMPLATE
+// (mis. {tenant}/{yyyy}/{mm}/{id}{ext})
+func objectNamer() (utils.Namer, error) {
+	namer := utils.Namer{
+		Scheme:   utils.NameRandom,
+		Length:   int(envInt64("OBJECT_NAME_LENGTH", utils.DefaultNameLength)),
+		Template: utils.DefaultNameTemplate,
+	}
+	if v := os.Getenv("OBJECT_NAME_SCHEME"); v != "" {
+		scheme, err := utils.ParseNameScheme(v)
+		if err != nil {
+			return namer, err
+		}
+		namer.Scheme = scheme
+	}
+	if v := os.Getenv("OBJECT_NAME_TEMPLATE"); v != "" {
+		namer.Template = v
+	}
+	return namer, namer.Validate()
+}
+
+// Buang metadata gambar (EXIF/XMP/IPTC, chunk teks PNG) per route dari env
+// STRIP_IMAGE_METADATA_<ROUTE>, fallback ke STRIP_IMAGE_METADATA, default aktif
+func stripMetadataEnabled(route string) bool {
+	return envBool("STRIP_IMAGE_METADATA_"+route, envBool("STRIP_IMAGE_METADATA", true))
+}
+
+// Tag EXIF yang disalin ke custom metadata sebelum dibuang, per route dari env
+// EXIF_KEEP_TAGS_<ROUTE> (mis. DateTimeOriginal,Make,Model), fallback ke
+// EXIF_KEEP_TAGS. Default kosong, semua tag dibuang.
+func exifKeepTags(route string) []string {
+	v := os.Getenv("EXIF_KEEP_TAGS_" + route)
+	if v == "" {
+		v = os.Getenv("EXIF_KEEP_TAGS")
+	}
+
+	var tags []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+// Daemon clamd untuk scan malware dari env CLAMD_ADDRESS (tcp://host:3310 atau
+// unix:///run/clamav/clamd.sock) dan CLAMD_TIMEOUT (detik, default 120).
+// Kosong berarti scanning mati.
+func malwareScanner() (*utils.Clamd, error) {
+	scanner, err := utils.ParseClamdAddress(os.Getenv("CLAMD_ADDRESS"))
+	if err != nil {
+		return nil, err
+	}
+	scanner.Timeout = time.Duration(envInt64("CLAMD_TIMEOUT", 120)) * time.Second
+	return scanner, nil
+}
+
+// Umur upload session sejak chunk terakhir dari env UPLOAD_SESSION_TTL
+// (detik, default 86400). Session yang belum selesai setelah itu dianggap
+// ditinggalkan.
+func uploadSessionTTL() time.Duration {
+	return time.Duration(envInt64("UPLOAD_SESSION_TTL", 86400)) * time.Second
+}
+
+func scanningEnabled() bool {
+	return os.Getenv("CLAMD_ADDRESS") != ""
+}
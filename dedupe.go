@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"firebase-poc/utils"
+
+	"cloud.google.com/go/firestore"
+	cloudStorage "cloud.google.com/go/storage"
+	"firebase.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Collection Firestore untuk reference count blob, satu dokumen per SHA-256.
+// Referensi per file logis disimpan di subcollection refs.
+const (
+	blobCollection    = "blobs"
+	blobRefCollection = "refs"
+)
+
+// Prefix object blob content-addressed: blobs/<sha256><ext>
+const blobPrefix = "blobs/"
+
+// Dokumen yang masih deleting lebih lama dari ini dianggap sisa proses yang
+// mati di tengah jalan, boleh diambil alih upload baru
+const blobDeleteTimeout = time.Minute
+
+// Jumlah percobaan acquire kalau blob sedang dihapus
+const maxBlobAcquireAttempts = 5
+
+var (
+	errBlobNotFound    = errors.New("blob not found")
+	errBlobRefNotFound = errors.New("blob reference not found")
+	errBlobBusy        = errors.New("blob is being deleted, try again")
+	errBlobManaged     = errors.New("object is managed by deduplicated storage, release its reference instead")
+)
+
+type blobDoc struct {
+	ObjectName  string    `firestore:"object_name"`
+	ContentType string    `firestore:"content_type"`
+	Size        int64     `firestore:"size"`
+	RefCount    int64     `firestore:"ref_count"`
+	Deleting    bool      `firestore:"deleting"`
+	CreatedAt   time.Time `firestore:"created_at"`
+	UpdatedAt   time.Time `firestore:"updated_at"`
+}
+
+type blobRef struct {
+	OriginalName string    `firestore:"original_name"`
+	CreatedAt    time.Time `firestore:"created_at"`
+}
+
+// Hasil simpan ke blob: object yang dipakai, id referensi untuk file logis ini,
+// dan apakah isi yang sama sudah pernah di-upload sebelumnya
+type blobResult struct {
+	ObjectName   string
+	SHA256       string
+	FileID       string
+	Deduplicated bool
+}
+
+func isBlobObject(objectPath string) bool {
+	return strings.HasPrefix(objectPath, blobPrefix)
+}
+
+func blobObjectName(sum string, ext string) string {
+	return blobPrefix + sum + ext
+}
+
+// SHA-256 dalam hex lowercase, juga dipakai sebagai id dokumen
+func validBlobHash(sum string) bool {
+	if len(sum) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(sum)
+	return err == nil
+}
+
+// Simpan data yang sudah ada di memory sebagai blob content-addressed
+// metadata (mis. hasil scan) ditambahkan ke custom metadata object blob.
+func storeBlob(ctx context.Context, client *storage.Client, fs *firestore.Client, data []byte, fileType utils.FileType, originalName string, metadata map[string]string) (*blobResult, error) {
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+	objectName := blobObjectName(sum, fileType.Ext())
+
+	// Referensi dicatat dulu baru object ditulis, supaya blob tidak bisa
+	// dihapus release lain setelah kita anggap ada
+	refID, existed, err := acquireBlobRef(ctx, fs, sum, objectName, fileType.MimeType, int64(len(data)), originalName)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := bucket.Object(objectName).If(cloudStorage.Conditions{DoesNotExist: true})
+	err = writeObject(ctx, obj, fileType.MimeType, mergeMetadata(metadata, map[string]string{"sha256": sum}), data)
+	if err != nil && !isNameCollision(err) {
+		releaseBlobRef(context.Background(), client, fs, sum, refID)
+		return nil, err
+	}
+
+	return &blobResult{ObjectName: objectName, SHA256: sum, FileID: refID, Deduplicated: existed}, nil
+}
+
+// Pindahkan object sementara hasil streaming ke blob content-addressed.
+// Kalau blob dengan isi yang sama sudah ada, object sementara cukup dihapus.
+func promoteToBlob(ctx context.Context, client *storage.Client, fs *firestore.Client, tmpName string, sum string, contentType string, ext string, size int64, originalName string, metadata map[string]string) (*blobResult, error) {
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return nil, err
+	}
+	defer bucket.Object(tmpName).Delete(context.Background())
+
+	objectName := blobObjectName(sum, ext)
+	refID, existed, err := acquireBlobRef(ctx, fs, sum, objectName, contentType, size, originalName)
+	if err != nil {
+		return nil, err
+	}
+
+	copier := bucket.Object(objectName).If(cloudStorage.Conditions{DoesNotExist: true}).CopierFrom(bucket.Object(tmpName))
+	copier.ContentType = contentType
+	copier.Metadata = mergeMetadata(metadata, map[string]string{"sha256": sum})
+	if _, err := copier.Run(ctx); err != nil && !isNameCollision(err) {
+		releaseBlobRef(context.Background(), client, fs, sum, refID)
+		return nil, err
+	}
+
+	return &blobResult{ObjectName: objectName, SHA256: sum, FileID: refID, Deduplicated: existed}, nil
+}
+
+// Tambah satu referensi ke blob. Kalau blob sedang dihapus release lain,
+// tunggu sebentar lalu coba lagi.
+func acquireBlobRef(ctx context.Context, fs *firestore.Client, sum string, objectName string, contentType string, size int64, originalName string) (string, bool, error) {
+	refID, err := newSessionID()
+	if err != nil {
+		return "", false, err
+	}
+
+	blobRefDoc := fs.Collection(blobCollection).Doc(sum)
+	refDoc := blobRefDoc.Collection(blobRefCollection).Doc(refID)
+
+	for attempt := 1; ; attempt++ {
+		existed := false
+		err := fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			existed = false
+			now := time.Now()
+			blob := blobDoc{ObjectName: objectName, ContentType: contentType, Size: size, CreatedAt: now}
+
+			doc, err := tx.Get(blobRefDoc)
+			switch {
+			case status.Code(err) == codes.NotFound:
+			case err != nil:
+				return err
+			default:
+				if err := doc.DataTo(&blob); err != nil {
+					return err
+				}
+				if blob.Deleting && now.Sub(blob.UpdatedAt) < blobDeleteTimeout {
+					return errBlobBusy
+				}
+				existed = !blob.Deleting
+				if blob.Deleting {
+					blob.RefCount = 0
+				}
+			}
+
+			blob.RefCount++
+			blob.Deleting = false
+			blob.UpdatedAt = now
+			if err := tx.Set(blobRefDoc, &blob); err != nil {
+				return err
+			}
+			return tx.Create(refDoc, &blobRef{OriginalName: originalName, CreatedAt: now})
+		})
+		if err == nil {
+			return refID, existed, nil
+		}
+		if !errors.Is(err, errBlobBusy) || attempt == maxBlobAcquireAttempts {
+			return "", false, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		case <-time.After(time.Duration(attempt) * 200 * time.Millisecond):
+		}
+	}
+}
+
+// Lepas satu referensi. Kalau itu referensi terakhir, object blob dihapus
+// lalu dokumennya. Selama object dihapus dokumen ditandai deleting, jadi
+// acquire yang datang bersamaan menunggu dan tidak memakai object yang
+// sebentar lagi hilang.
+func releaseBlobRef(ctx context.Context, client *storage.Client, fs *firestore.Client, sum string, refID string) (bool, error) {
+	blobRefDoc := fs.Collection(blobCollection).Doc(sum)
+	refDoc := blobRefDoc.Collection(blobRefCollection).Doc(refID)
+
+	var blob blobDoc
+	err := fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(blobRefDoc)
+		if status.Code(err) == codes.NotFound {
+			return errBlobNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Get(refDoc); status.Code(err) == codes.NotFound {
+			return errBlobRefNotFound
+		} else if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&blob); err != nil {
+			return err
+		}
+
+		blob.RefCount--
+		if blob.RefCount <= 0 {
+			blob.RefCount = 0
+			blob.Deleting = true
+		}
+		blob.UpdatedAt = time.Now()
+		if err := tx.Delete(refDoc); err != nil {
+			return err
+		}
+		return tx.Set(blobRefDoc, &blob)
+	})
+	if err != nil || !blob.Deleting {
+		return false, err
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return false, err
+	}
+	if err := bucket.Object(blob.ObjectName).Delete(ctx); err != nil && !errors.Is(err, cloudStorage.ErrObjectNotExist) {
+		return false, err
+	}
+	deleteDerivedObjects(ctx, bucket, blob.ObjectName)
+
+	// Hapus dokumen hanya kalau belum diambil alih upload baru
+	err = fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(blobRefDoc)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var current blobDoc
+		if err := doc.DataTo(&current); err != nil {
+			return err
+		}
+		if !current.Deleting || current.RefCount > 0 {
+			return nil
+		}
+		return tx.Delete(blobRefDoc)
+	})
+	return true, err
+}
+
+// Handler GET /blobs/:sha256, cek apakah isi dengan hash ini sudah pernah di-upload
+func getBlobHandler(c *gin.Context) {
+	sum := strings.ToLower(c.Param("sha256"))
+	if !validBlobHash(sum) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sha256"})
+		return
+	}
+
+	doc, err := firestoreClient.Collection(blobCollection).Doc(sum).Get(c)
+	if status.Code(err) == codes.NotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": errBlobNotFound.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var blob blobDoc
+	if err := doc.DataTo(&blob); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if blob.Deleting {
+		c.JSON(http.StatusNotFound, gin.H{"error": errBlobNotFound.Error()})
+		return
+	}
+
+	signedURL, rawURL, err := GenerateURL(blob.ObjectName, 30, client)
+	if err != nil {
+		c.JSON(urlErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sha256":       sum,
+		"object_name":  blob.ObjectName,
+		"content_type": blob.ContentType,
+		"size":         blob.Size,
+		"ref_count":    blob.RefCount,
+		"signed_url":   signedURL,
+		"raw_url":      rawURL,
+	})
+}
+
+// Handler DELETE /blobs/:sha256/refs/:id, lepas referensi file logis.
+// Blob baru benar-benar dihapus saat referensi terakhir dilepas.
+func releaseBlobRefHandler(c *gin.Context) {
+	sum := strings.ToLower(c.Param("sha256"))
+	if !validBlobHash(sum) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sha256"})
+		return
+	}
+
+	deleted, err := releaseBlobRef(c, client, firestoreClient, sum, c.Param("id"))
+	switch {
+	case errors.Is(err, errBlobNotFound), errors.Is(err, errBlobRefNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"sha256": sum, "blob_deleted": deleted})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"firebase-poc/utils"
+)
+
+// Prefix key custom metadata untuk tag EXIF yang dipertahankan, mis. exif_datetimeoriginal
+const exifMetadataPrefix = "exif_"
+
+// Kualitas JPEG saat gambar harus di-encode ulang untuk menerapkan orientasi
+const reorientQuality = 92
+
+// Buang metadata gambar PNG/JPEG dari src sebelum ditulis ke bucket (lihat
+// utils.StripImageMetadata). Format lain dikembalikan apa adanya. Kalau ada
+// orientasi EXIF, piksel diputar dulu supaya gambar tetap tegak setelah
+// tagnya hilang; hanya kasus ini yang perlu decode dan encode ulang.
+// Tag yang ada di keep dikembalikan sebagai custom metadata.
+func sanitizeImage(src io.Reader, contentType string, keep []string, maxSize int64) (io.Reader, map[string]string, error) {
+	format, ok := thumbnailFormat(contentType)
+	if !ok {
+		return src, nil, nil
+	}
+
+	// Segmen yang dibuang tidak ikut terhitung di output, jadi input juga dibatasi
+	limit := &sizeLimitReader{r: src, n: maxSize}
+	stripped, meta, err := utils.StripImageMetadata(limit, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags := exifMetadata(meta.Tags, keep)
+	if meta.Orientation <= 1 {
+		return stripped, tags, nil
+	}
+
+	img, _, err := utils.DecodeImage(stripped, envInt64("MAX_IMAGE_PIXELS", defaultMaxImagePixels))
+	if limit.n < 0 {
+		return nil, nil, errUploadTooLarge
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	if err := utils.EncodeImage(&buf, utils.ApplyOrientation(img, meta.Orientation), format, reorientQuality); err != nil {
+		return nil, nil, err
+	}
+	return &buf, tags, nil
+}
+
+// Versi sanitizeImage untuk data yang sudah ada di memory
+func sanitizeImageData(data []byte, contentType string, keep []string) ([]byte, map[string]string, error) {
+	if _, ok := thumbnailFormat(contentType); !ok {
+		return data, nil, nil
+	}
+
+	r, tags, err := sanitizeImage(bytes.NewReader(data), contentType, keep, int64(len(data)))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = io.ReadAll(r)
+	return data, tags, err
+}
+
+// Pilih tag EXIF yang ada di whitelist (nama tag, tidak case-sensitive)
+func exifMetadata(tags map[string]string, keep []string) map[string]string {
+	result := map[string]string{}
+	for _, name := range keep {
+		for tag, value := range tags {
+			if strings.EqualFold(tag, name) && value != "" {
+				result[exifMetadataPrefix+strings.ToLower(tag)] = value
+			}
+		}
+	}
+	return result
+}
+
+// Gabungkan extra ke salinan metadata
+func mergeMetadata(metadata map[string]string, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return metadata
+	}
+	result := map[string]string{}
+	for k, v := range metadata {
+		result[k] = v
+	}
+	for k, v := range extra {
+		result[k] = v
+	}
+	return result
+}
+
+// Reader yang gagal dengan errUploadTooLarge begitu isinya melebihi n byte
+type sizeLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errUploadTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errUploadTooLarge
+	}
+	return n, err
+}
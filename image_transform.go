@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Subfolder hasil transformasi, di samping object aslinya:
+// users/1/abc.jpg -> users/1/_img/abc.jpg/<generation>/<w>x<h>-<fit>-q<q>.<ext>
+const imageCacheDir = "_img"
+
+const (
+	// Default batas w/h hasil transformasi (bisa di-override via IMG_MAX_DIMENSION)
+	defaultImageMaxDimension = 2048
+	// Default kualitas JPEG kalau q tidak diisi
+	defaultImageQuality = 85
+	// Jumlah transformasi yang boleh jalan bersamaan (decode/resize makan CPU dan memory)
+	maxConcurrentTransforms = 4
+)
+
+// Query yang ikut di-sign, urutan tidak penting karena di-encode terurut.
+// exp (unix detik) opsional, kalau diisi link tidak berlaku lagi setelahnya.
+var imageParams = []string{"w", "h", "fit", "fmt", "q", "exp"}
+
+var imageTransformSlots = make(chan struct{}, maxConcurrentTransforms)
+
+var (
+	errImageSignature = errors.New("invalid image signature")
+	errImageExpired   = errors.New("image link has expired")
+)
+
+type imageTransform struct {
+	Width   int
+	Height  int
+	Fit     utils.Fit
+	Format  string
+	Quality int
+}
+
+// Signature HMAC-SHA256 (hex) atas path object dan parameter transformasi.
+// Backend yang membuat link /img memakai key IMG_SIGNING_KEY yang sama:
+// sig = hex(HMAC(key, "<path>?" + query w,h,fit,fmt,q,exp yang terisi, url-encoded dan urut key))
+func imageSignature(key []byte, objectPath string, query url.Values) string {
+	params := url.Values{}
+	for _, name := range imageParams {
+		if v := query.Get(name); v != "" {
+			params.Set(name, v)
+		}
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(objectPath + "?" + params.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Cek sig dan, kalau ada, exp dari query link /img
+func verifyImageSignature(key []byte, objectPath string, query url.Values, now time.Time) error {
+	expected := imageSignature(key, objectPath, query)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("sig"))) {
+		return errImageSignature
+	}
+
+	if v := query.Get("exp"); v != "" {
+		exp, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return errImageSignature
+		}
+		if now.Unix() > exp {
+			return errImageExpired
+		}
+	}
+	return nil
+}
+
+// Baca dan validasi parameter transformasi. Format default mengikuti sumber.
+func parseImageTransform(query url.Values, sourceFormat string) (imageTransform, error) {
+	maxDimension := int(envInt64("IMG_MAX_DIMENSION", defaultImageMaxDimension))
+	t := imageTransform{Format: sourceFormat, Quality: defaultImageQuality}
+
+	var err error
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"w", &t.Width}, {"h", &t.Height}} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		*p.value, err = strconv.Atoi(v)
+		if err != nil || *p.value <= 0 || *p.value > maxDimension {
+			return t, fmt.Errorf("%s must be between 1 and %d", p.name, maxDimension)
+		}
+	}
+	if t.Width == 0 && t.Height == 0 {
+		return t, errors.New("w or h is required")
+	}
+
+	if t.Fit, err = utils.ParseFit(query.Get("fit")); err != nil {
+		return t, err
+	}
+
+	switch query.Get("fmt") {
+	case "":
+	case "png":
+		t.Format = "png"
+	case "jpeg", "jpg":
+		t.Format = "jpeg"
+	default:
+		return t, errors.New("fmt must be png or jpeg")
+	}
+
+	if v := query.Get("q"); v != "" {
+		t.Quality, err = strconv.Atoi(v)
+		if err != nil || t.Quality < 1 || t.Quality > 100 {
+			return t, errors.New("q must be between 1 and 100")
+		}
+	}
+	// Kualitas tidak berpengaruh ke PNG, jangan sampai jadi cache key berbeda
+	if t.Format == "png" {
+		t.Quality = 0
+	}
+
+	return t, nil
+}
+
+func (t imageTransform) contentType() string {
+	return "image/" + t.Format
+}
+
+// Prefix semua cache transformasi milik objectName, dari generation mana pun
+func imageCachePrefix(objectName string) string {
+	dir, base := path.Split(objectName)
+	return dir + imageCacheDir + "/" + base + "/"
+}
+
+// Nama object cache. Generation sumber ikut di key, jadi kalau object asli
+// ditimpa, hasil lama otomatis tidak dipakai lagi.
+func imageCacheName(objectName string, generation int64, t imageTransform) string {
+	ext := ".png"
+	if t.Format == "jpeg" {
+		ext = ".jpg"
+	}
+	return fmt.Sprintf("%s%d/%dx%d-%s-q%d%s", imageCachePrefix(objectName), generation, t.Width, t.Height, t.Fit, t.Quality, ext)
+}
+
+// Handler GET /img/*path?w=&h=&fit=&fmt=&q=&exp=&sig=
+// Resize/crop/convert gambar on demand. Hasilnya disimpan sebagai object
+// turunan dan dipakai ulang untuk request berikutnya. Parameter wajib di-sign
+// (lihat imageSignature) supaya orang luar tidak bisa memicu transformasi
+// sembarangan. ?redirect=true membalas 302 ke signed URL hasil cache.
+func imageTransformHandler(c *gin.Context) {
+	key := os.Getenv("IMG_SIGNING_KEY")
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "image transformation is not configured"})
+		return
+	}
+
+	objectPath, ok := objectPathParam(c)
+	if !ok {
+		return
+	}
+
+	query := c.Request.URL.Query()
+	if err := verifyImageSignature([]byte(key), objectPath, query, time.Now()); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := bucket.Object(objectPath).Attrs(c)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkScanned(source.Metadata); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	sourceFormat, ok := thumbnailFormat(source.ContentType)
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": errUnsupportedFormat.Error()})
+		return
+	}
+
+	t, err := parseImageTransform(query, sourceFormat)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cacheName := imageCacheName(objectPath, source.Generation, t)
+	cached, err := bucket.Object(cacheName).Attrs(c)
+	if errors.Is(err, cloudStorage.ErrObjectNotExist) {
+		cached, err = renderImage(c, bucket, source, cacheName, t)
+	}
+	if err != nil {
+		c.JSON(imageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if redirect, _ := strconv.ParseBool(c.Query("redirect")); redirect {
+		signedURL, _, err := GenerateURL(cacheName, 300, client)
+		if err != nil {
+			c.JSON(urlErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Redirect(http.StatusFound, signedURL)
+		return
+	}
+
+	serveObject(c, bucket, cached, "public, max-age=3600")
+}
+
+// Transformasi gambar sumber lalu simpan sebagai object cache
+func renderImage(c *gin.Context, bucket *cloudStorage.BucketHandle, source *cloudStorage.ObjectAttrs, cacheName string, t imageTransform) (*cloudStorage.ObjectAttrs, error) {
+	select {
+	case imageTransformSlots <- struct{}{}:
+		defer func() { <-imageTransformSlots }()
+	case <-c.Request.Context().Done():
+		return nil, c.Request.Context().Err()
+	}
+
+	r, err := bucket.Object(source.Name).Generation(source.Generation).NewReader(c)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	img, _, err := utils.DecodeImage(r, envInt64("MAX_IMAGE_PIXELS", defaultMaxImagePixels))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := utils.EncodeImage(&buf, utils.Transform(img, t.Width, t.Height, t.Fit), t.Format, t.Quality); err != nil {
+		return nil, err
+	}
+
+	// Hasil scan sumber ikut disalin supaya cache bisa di-sign
+	metadata := mergeMetadata(scanMetadataOf(source.Metadata), map[string]string{
+		"source_object":     source.Name,
+		"source_generation": strconv.FormatInt(source.Generation, 10),
+	})
+	// Request lain bisa saja sudah menulis cache yang sama duluan, itu tidak masalah
+	obj := bucket.Object(cacheName).If(cloudStorage.Conditions{DoesNotExist: true})
+	if err := writeObject(c, obj, t.contentType(), metadata, buf.Bytes()); err != nil && !isNameCollision(err) {
+		return nil, err
+	}
+
+	return bucket.Object(cacheName).Attrs(c)
+}
+
+func imageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, utils.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, utils.ErrInvalidImage):
+		// Isi object tidak sesuai Content-Type-nya
+		return http.StatusUnprocessableEntity
+	default:
+		return storageErrorStatus(err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"firebase-poc/utils"
+)
+
+func signedImageQuery(key []byte, objectPath string, raw string) url.Values {
+	query, _ := url.ParseQuery(raw)
+	query.Set("sig", imageSignature(key, objectPath, query))
+	return query
+}
+
+func TestImageSignature(t *testing.T) {
+	key := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	future := strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	past := strconv.FormatInt(now.Add(-time.Second).Unix(), 10)
+
+	tests := []struct {
+		name   string
+		path   string
+		query  url.Values
+		tamper func(url.Values)
+		err    error
+	}{
+		{"valid", "a/b.png", signedImageQuery(key, "a/b.png", "w=100&fit=cover"), nil, nil},
+		// Urutan query dan parameter di luar imageParams tidak mempengaruhi sig
+		{"extra params", "a/b.png", signedImageQuery(key, "a/b.png", "fit=cover&w=100"), func(q url.Values) { q.Set("redirect", "true") }, nil},
+		{"valid exp", "a/b.png", signedImageQuery(key, "a/b.png", "w=100&exp="+future), nil, nil},
+		{"expired", "a/b.png", signedImageQuery(key, "a/b.png", "w=100&exp="+past), nil, errImageExpired},
+		{"exp removed", "a/b.png", signedImageQuery(key, "a/b.png", "w=100&exp="+past), func(q url.Values) { q.Del("exp") }, errImageSignature},
+		{"exp extended", "a/b.png", signedImageQuery(key, "a/b.png", "w=100&exp="+past), func(q url.Values) { q.Set("exp", future) }, errImageSignature},
+		{"invalid exp", "a/b.png", signedImageQuery(key, "a/b.png", "w=100&exp=soon"), nil, errImageSignature},
+		{"width changed", "a/b.png", signedImageQuery(key, "a/b.png", "w=100"), func(q url.Values) { q.Set("w", "2000") }, errImageSignature},
+		{"param added", "a/b.png", signedImageQuery(key, "a/b.png", "w=100"), func(q url.Values) { q.Set("fmt", "jpeg") }, errImageSignature},
+		{"other path", "a/c.png", signedImageQuery(key, "a/b.png", "w=100"), nil, errImageSignature},
+		{"other key", "a/b.png", signedImageQuery([]byte("other"), "a/b.png", "w=100"), nil, errImageSignature},
+		{"missing sig", "a/b.png", url.Values{"w": {"100"}}, nil, errImageSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.tamper != nil {
+				tt.tamper(tt.query)
+			}
+			if err := verifyImageSignature(key, tt.path, tt.query, now); !errors.Is(err, tt.err) {
+				t.Fatalf("verifyImageSignature = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseImageTransform(t *testing.T) {
+	t.Setenv("IMG_MAX_DIMENSION", "1000")
+
+	tests := []struct {
+		query  string
+		source string
+		want   imageTransform
+		err    bool
+	}{
+		{"w=100", "jpeg", imageTransform{Width: 100, Fit: utils.FitContain, Format: "jpeg", Quality: defaultImageQuality}, false},
+		{"h=50&fit=cover&q=60", "jpeg", imageTransform{Height: 50, Fit: utils.FitCover, Format: "jpeg", Quality: 60}, false},
+		{"w=100&h=100&fmt=jpg&q=70", "png", imageTransform{Width: 100, Height: 100, Fit: utils.FitContain, Format: "jpeg", Quality: 70}, false},
+		// Kualitas diabaikan untuk PNG
+		{"w=1000&fmt=png&q=70", "jpeg", imageTransform{Width: 1000, Fit: utils.FitContain, Format: "png"}, false},
+		{"", "png", imageTransform{}, true},
+		{"w=0", "png", imageTransform{}, true},
+		{"w=-1", "png", imageTransform{}, true},
+		{"w=1001", "png", imageTransform{}, true},
+		{"w=100&h=1001", "png", imageTransform{}, true},
+		{"w=abc", "png", imageTransform{}, true},
+		{"w=100&fit=stretch", "png", imageTransform{}, true},
+		{"w=100&fmt=gif", "png", imageTransform{}, true},
+		{"w=100&q=0", "jpeg", imageTransform{}, true},
+		{"w=100&q=101", "jpeg", imageTransform{}, true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := parseImageTransform(query, tt.source)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseImageTransform(%q) = %+v, want error", tt.query, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseImageTransform(%q) = %+v, %v, want %+v", tt.query, got, err, tt.want)
+		}
+	}
+}
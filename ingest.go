@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"firebase-poc/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Default timeout seluruh fetch termasuk body (bisa di-override via INGEST_TIMEOUT, detik)
+	defaultIngestTimeout = 30
+	// Default batas redirect (bisa di-override via INGEST_MAX_REDIRECTS)
+	defaultIngestMaxRedirects = 3
+	// Default scheme yang boleh di-fetch (bisa di-override via INGEST_ALLOWED_SCHEMES)
+	defaultIngestSchemes = "https"
+)
+
+var (
+	errIngestScheme           = errors.New("url scheme not allowed")
+	errIngestBlockedAddress   = errors.New("destination address not allowed")
+	errIngestTooManyRedirects = errors.New("too many redirects")
+)
+
+// Range alamat yang tidak boleh dituju server: private, loopback, link-local,
+// multicast, dokumentasi, dan prefix IPv6 yang membungkus alamat IPv4
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.0.0.0/24", "192.0.2.0/24", "192.168.0.0/16", "198.18.0.0/15",
+	"198.51.100.0/24", "203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "64:ff9b::/96", "100::/64", "2001::/32", "2001:db8::/32",
+	"2002::/16", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func blockedIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler POST /ingest, ambil file dari URL remote lalu simpan ke bucket.
+// Fetch dibatasi waktu dan ukuran, dan tujuan dicek terhadap SSRF.
+func ingestHandler(c *gin.Context) {
+	var req types.UrlFile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix, err := uploadPrefix(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := url.Parse(req.Url)
+	if err != nil || source.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid url"})
+		return
+	}
+	if !ingestSchemeAllowed(source.Scheme) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errIngestScheme.Error()})
+		return
+	}
+
+	maxSize := envInt64("MAX_INGEST_SIZE", defaultMaxUploadSize)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(envInt64("INGEST_TIMEOUT", defaultIngestTimeout))*time.Second)
+	defer cancel()
+
+	fetchReq, err := http.NewRequestWithContext(ctx, http.MethodGet, source.String(), nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fetchReq.Header.Set("User-Agent", "firebase-poc-ingest")
+
+	resp, err := newIngestClient().Do(fetchReq)
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("remote returned %s", resp.Status)})
+		return
+	}
+	if resp.ContentLength > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errUploadTooLarge.Error()})
+		return
+	}
+
+	// Nama file diambil dari URL terakhir setelah redirect
+	fileName := path.Base(resp.Request.URL.Path)
+	if fileName == "/" || fileName == "." {
+		fileName = ""
+	}
+
+	metadata := originalNameMetadata(fileName)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["source_url"] = sourceURLMetadata(source)
+
+	policy, checkDeclared := ingestMismatchPolicy()
+	declaredName, declaredType := "", ""
+	if checkDeclared {
+		declaredName, declaredType = fileName, resp.Header.Get("Content-Type")
+	}
+
+	result, err := streamObject(ctx, client, resp.Body, streamOptions{
+		Prefix:              prefix,
+		Tenant:              uploadTenant(c),
+		Metadata:            metadata,
+		Allow:               allowedTypes("INGEST"),
+		Policy:              policy,
+		Dedupe:              dedupeEnabled("INGEST"),
+		Thumbnails:          thumbnailSizes("INGEST"),
+		StripMetadata:       stripMetadataEnabled("INGEST"),
+		KeepTags:            exifKeepTags("INGEST"),
+		DeclaredName:        declaredName,
+		DeclaredContentType: declaredType,
+		MaxSize:             maxSize,
+	})
+	if err != nil {
+		respondIngestError(c, err)
+		return
+	}
+
+	respondStreamResult(c, result)
+}
+
+func respondIngestError(c *gin.Context, err error) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errIngestScheme):
+		c.JSON(http.StatusBadRequest, gin.H{"error": errIngestScheme.Error()})
+	case errors.Is(err, errIngestBlockedAddress):
+		c.JSON(http.StatusBadRequest, gin.H{"error": errIngestBlockedAddress.Error()})
+	case errors.Is(err, errIngestTooManyRedirects):
+		c.JSON(http.StatusBadGateway, gin.H{"error": errIngestTooManyRedirects.Error()})
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "timed out fetching remote file"})
+	case errors.As(err, &netErr):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		respondUploadError(c, err)
+	}
+}
+
+func ingestSchemeAllowed(scheme string) bool {
+	allowed := os.Getenv("INGEST_ALLOWED_SCHEMES")
+	if allowed == "" {
+		allowed = defaultIngestSchemes
+	}
+	for _, s := range strings.Split(allowed, ",") {
+		if strings.EqualFold(strings.TrimSpace(s), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// URL sumber tanpa userinfo dan query, supaya token di URL tidak ikut tersimpan
+func sourceURLMetadata(u *url.URL) string {
+	clean := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	return clean.String()
+}
+
+// HTTP client khusus ingest. Proxy dimatikan, redirect dibatasi dan scheme-nya
+// dicek ulang, dan setiap koneksi lewat ingestDialer.
+func newIngestClient() *http.Client {
+	maxRedirects := int(envInt64("INGEST_MAX_REDIRECTS", defaultIngestMaxRedirects))
+	dialer := &ingestDialer{resolved: map[string]net.IP{}}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			DisableKeepAlives:   true,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return errIngestTooManyRedirects
+			}
+			if !ingestSchemeAllowed(req.URL.Scheme) {
+				return errIngestScheme
+			}
+			return nil
+		},
+	}
+}
+
+// ingestDialer resolve DNS sekali per host, menolak kalau ada alamat hasil
+// resolve yang masuk blockedNetworks, lalu dial langsung ke IP tersebut.
+// Hasil resolve di-pin untuk sisa request, jadi DNS rebinding tidak bisa
+// mengarahkan koneksi berikutnya ke alamat internal.
+type ingestDialer struct {
+	mu       sync.Mutex
+	resolved map[string]net.IP
+}
+
+func (d *ingestDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+}
+
+func (d *ingestDialer) resolve(ctx context.Context, host string) (net.IP, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if ip, ok := d.resolved[host]; ok {
+		return ip, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return nil, errIngestBlockedAddress
+		}
+	}
+
+	d.resolved[host] = addrs[0].IP
+	return addrs[0].IP, nil
+}
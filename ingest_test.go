@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"2606:4700:4700::1111", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"fd12:3456::1", true},
+		{"ff02::1", true},
+		{"2001:db8::1", true},
+		// IPv4-mapped IPv6 dicek sebagai IPv4-nya
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"::ffff:8.8.8.8", false},
+		// NAT64, 6to4 dan Teredo bisa membungkus alamat IPv4 internal
+		{"64:ff9b::7f00:1", true},
+		{"64:ff9b::808:808", true},
+		{"2002:7f00:1::1", true},
+		{"2001::1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			ip := net.ParseIP(tt.ip)
+			if ip == nil {
+				t.Fatalf("invalid test ip %q", tt.ip)
+			}
+			if got := blockedIP(ip); got != tt.blocked {
+				t.Fatalf("blockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestIngestDialerResolve(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+		err  error
+	}{
+		{"8.8.8.8", "8.8.8.8", nil},
+		{"::ffff:8.8.8.8", "8.8.8.8", nil},
+		{"127.0.0.1", "", errIngestBlockedAddress},
+		{"::ffff:127.0.0.1", "", errIngestBlockedAddress},
+		{"64:ff9b::a00:1", "", errIngestBlockedAddress},
+		{"::1", "", errIngestBlockedAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			d := &ingestDialer{resolved: map[string]net.IP{}}
+			ip, err := d.resolve(context.Background(), tt.host)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("resolve(%s) = %v, %v, want %v", tt.host, ip, err, tt.err)
+				}
+				if _, ok := d.resolved[tt.host]; ok {
+					t.Fatalf("blocked host %s was pinned", tt.host)
+				}
+				return
+			}
+			if err != nil || !ip.Equal(net.ParseIP(tt.want)) {
+				t.Fatalf("resolve(%s) = %v, %v, want %s", tt.host, ip, err, tt.want)
+			}
+			if !d.resolved[tt.host].Equal(ip) {
+				t.Fatalf("resolve(%s) did not pin %v", tt.host, ip)
+			}
+		})
+	}
+}
+
+// Host yang sudah di-pin tidak di-resolve ulang, jadi DNS rebinding tidak
+// bisa mengganti alamatnya di tengah request
+func TestIngestDialerResolvePinned(t *testing.T) {
+	pinned := net.ParseIP("93.184.216.34")
+	d := &ingestDialer{resolved: map[string]net.IP{"rebind.invalid": pinned}}
+
+	for i := 0; i < 2; i++ {
+		ip, err := d.resolve(context.Background(), "rebind.invalid")
+		if err != nil || !ip.Equal(pinned) {
+			t.Fatalf("resolve = %v, %v, want pinned %v", ip, err, pinned)
+		}
+	}
+}
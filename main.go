@@ -3,14 +3,20 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
+	"firebase-poc/utils"
+
 	"cloud.google.com/go/firestore"
 	cloudStorage "cloud.google.com/go/storage"
 	firebase "firebase.google.com/go"
@@ -24,7 +30,9 @@ import (
 var client *storage.Client
 var firestoreClient *firestore.Client
 
-func init() {
+// Load .env dan init client Firebase. Dipanggil dari main, bukan init, supaya
+// test di package main bisa jalan tanpa .env dan kredensial.
+func initFirebase() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatalf("Error loading .env file: %v", err)
@@ -86,16 +94,46 @@ func init() {
 }
 
 func main() {
+	initFirebase()
+
 	r := gin.Default()
 
 	// Endpoint untuk get raw url and signed url
-	r.GET("/url/:filename", func(c *gin.Context) {
-		// Dapatkan nama file dari parameter URL
-		filename := c.Param("filename")
+	r.GET("/url/*path", func(c *gin.Context) {
+		// Dapatkan path object dari parameter URL
+		filename, ok := objectPathParam(c)
+		if !ok {
+			return
+		}
+
+		// ?generation=N untuk versi lama object
+		opts, err := generationOptions(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		signedURL, rawURL, err := GenerateURL(filename, 30, client) // 30 second ttl biar bisa liat2 dulu
+		// ?variant=N untuk thumbnail
+		variant, err := variantOptions(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts = append(opts, variant...)
+
+		// Disposition hanya di-bake ke signed url kalau diminta
+		if c.Query("disposition") != "" || c.Query("content_type") != "" {
+			overrides, err := responseOverrideOptions(c, filename, "attachment")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			opts = append(opts, overrides...)
+		}
+
+		signedURL, rawURL, err := GenerateURL(filename, 30, client, opts...) // 30 second ttl biar bisa liat2 dulu
+		if err != nil {
+			c.JSON(urlErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
@@ -107,35 +145,51 @@ func main() {
 	})
 
 	// Endpoint untuk download signed url
-	r.GET("/download-signed/:filename", func(c *gin.Context) {
-		// Get the filename from the URL parameter
-		filename := c.Param("filename")
+	r.GET("/download-signed/*path", func(c *gin.Context) {
+		// Get the object path from the URL parameter
+		filename, ok := objectPathParam(c)
+		if !ok {
+			return
+		}
+
+		// Header response ikut di-sign ke url, karena header di response redirect diabaikan browser
+		opts, err := responseOverrideOptions(c, filename, "attachment")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		generationOpts, err := generationOptions(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts = append(opts, generationOpts...)
 
 		// Generate the signed URL for downloading the file
-		url, _, err := GenerateURL(filename, 5, client) // 5 second ttl karena langsung download
+		url, _, err := GenerateURL(filename, 5, client, opts...) // 5 second ttl karena langsung download
 		if err != nil {
 			if strings.Contains(err.Error(), "token expired") {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
 			} else if strings.Contains(err.Error(), "no keys") {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "No keys available"})
 			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				c.JSON(urlErrorStatus(err), gin.H{"error": err.Error()})
 			}
 			return
 		}
 
-		// Set appropriate headers for the file download
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-		c.Header("Content-Type", "application/octet-stream")
-
 		// Trigger the file download by redirecting the client to the signed URL
 		c.Redirect(http.StatusFound, url)
 	})
 
 	// Endpoint untuk download unsigned url
-	r.GET("/download-unsigned/:filename", func(c *gin.Context) {
-		// Get the filename from the URL parameter
-		filename := c.Param("filename")
+	r.GET("/download-unsigned/*path", func(c *gin.Context) {
+		// Get the object path from the URL parameter
+		filename, ok := objectPathParam(c)
+		if !ok {
+			return
+		}
 
 		// Generate the signed URL for downloading the file
 		_, url, err := GenerateURL(filename, 5, client) // 5 second ttl karena langsung download
@@ -145,19 +199,78 @@ func main() {
 			} else if strings.Contains(err.Error(), "no keys") {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "No keys available"})
 			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				c.JSON(urlErrorStatus(err), gin.H{"error": err.Error()})
 			}
 			return
 		}
 
-		// Set appropriate headers for the file download
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-		c.Header("Content-Type", "application/octet-stream")
-
-		// Trigger the file download by redirecting the client to the signed URL
+		// Header di response redirect diabaikan browser dan url unsigned tidak
+		// bisa membawa override, pakai /download-signed kalau butuh nama file
 		c.Redirect(http.StatusFound, url)
 	})
 
+	// Endpoint untuk download lewat server (streaming, support Range dan ETag)
+	r.GET("/download-proxy/*path", downloadProxyHandler)
+	r.HEAD("/download-proxy/*path", downloadProxyHandler)
+
+	// Endpoint untuk list object di bucket (prefix, delimiter, pagination)
+	r.GET("/objects", listObjectsHandler)
+
+	// Endpoint untuk metadata object (HEAD, GET ?action=meta, PATCH ?action=meta)
+	r.HEAD("/objects/*path", headObjectHandler)
+	r.GET("/objects/*path", objectGetHandler)
+	r.PATCH("/objects/*path", objectPatchHandler)
+
+	// Endpoint untuk delete, copy dan move/rename object (?action=copy|move|rename, dengan precondition generation)
+	r.DELETE("/objects/*path", deleteObjectHandler)
+	r.POST("/objects/*path", objectPostHandler)
+
+	// Endpoint untuk download banyak object sekaligus sebagai satu ZIP (streaming)
+	r.POST("/archive", archiveHandler)
+
+	// Endpoint untuk upload file base64 dalam JSON (di-buffer di memory)
+	r.POST("/upload", uploadHandler)
+
+	// Endpoint untuk upload banyak file base64 sekaligus
+	r.POST("/upload-batch", uploadBatchHandler)
+
+	// Endpoint untuk ambil file dari URL remote ke bucket
+	r.POST("/ingest", ingestHandler)
+
+	// Endpoint untuk cek blob dedupe berdasarkan SHA-256 dan melepas referensinya
+	r.GET("/blobs/:sha256", getBlobHandler)
+	r.DELETE("/blobs/:sha256/refs/:id", releaseBlobRefHandler)
+
+	// Endpoint untuk resize/crop/convert gambar on the fly (parameter wajib di-sign)
+	r.GET("/img/*path", imageTransformHandler)
+
+	// Endpoint untuk upload body base64 / data URI mentah (decode streaming)
+	r.POST("/upload-base64", uploadBase64StreamHandler)
+
+	// Endpoint untuk upload multipart/form-data (streaming, tanpa buffer di memory)
+	r.POST("/upload-multipart", uploadMultipartHandler)
+
+	// Endpoint untuk generate signed PUT url (upload langsung dari browser ke bucket)
+	r.POST("/upload-url", uploadURLHandler)
+
+	// Endpoint untuk generate signed POST policy (upload dari form HTML)
+	r.POST("/upload-policy", uploadPolicyHandler)
+
+	// Endpoint untuk resumable upload (chunk + Content-Range, state di Firestore)
+	r.POST("/uploads", createUploadSessionHandler)
+	r.GET("/uploads/:id", uploadSessionStatusHandler)
+	r.PUT("/uploads/:id", uploadChunkHandler)
+	r.POST("/uploads/:id/complete", completeUploadSessionHandler)
+
+	// Endpoint tus 1.0 (creation, termination, checksum), dipakai client Uppy / tus-js-client
+	tus := r.Group("/files", tusResumableMiddleware)
+	tus.OPTIONS("", tusOptionsHandler)
+	tus.OPTIONS("/:id", tusOptionsHandler)
+	tus.POST("", tusCreateHandler)
+	tus.HEAD("/:id", tusHeadHandler)
+	tus.PATCH("/:id", tusPatchHandler)
+	tus.DELETE("/:id", tusDeleteHandler)
+
 	r.POST("/data-firestore-sdk/:data", func(c *gin.Context) {
 		data := c.Param("data")
 
@@ -215,32 +328,273 @@ func main() {
 	r.Run()
 }
 
+// Opsi tambahan untuk GenerateURL, mengubah SignedURLOptions sebelum di-sign
+type URLOption func(*urlOptions)
+
+type urlOptions struct {
+	cloudStorage.SignedURLOptions
+	// Ukuran thumbnail yang di-sign, 0 berarti object aslinya
+	Variant int
+}
+
+// Upload hanya berhasil kalau object belum ada, lewat signed header
+// x-goog-if-generation-match: 0
+func WithDoesNotExist() URLOption {
+	return func(opts *urlOptions) {
+		opts.Headers = append(opts.Headers, "x-goog-if-generation-match:0")
+	}
+}
+
+// Wajibkan header custom metadata x-goog-meta-<key> dengan nilai persis value
+func WithMetadata(key string, value string) URLOption {
+	return func(opts *urlOptions) {
+		opts.Headers = append(opts.Headers, "x-goog-meta-"+key+":"+value)
+	}
+}
+
+// Ganti HTTP method yang di-sign (default GET)
+func WithMethod(method string) URLOption {
+	return func(opts *urlOptions) {
+		opts.Method = method
+	}
+}
+
+// Pakai skema signing V4 (wajib untuk signed header)
+func WithV4() URLOption {
+	return func(opts *urlOptions) {
+		opts.Scheme = cloudStorage.SigningSchemeV4
+	}
+}
+
+// Kunci Content-Type yang harus dikirim client
+func WithContentType(contentType string) URLOption {
+	return func(opts *urlOptions) {
+		opts.ContentType = contentType
+	}
+}
+
+// Kunci ukuran body lewat signed header x-goog-content-length-range
+func WithContentLength(size int64) URLOption {
+	return func(opts *urlOptions) {
+		opts.Headers = append(opts.Headers, "x-goog-content-length-range:"+formatLengthRange(size))
+	}
+}
+
+// Override Content-Type response lewat query response-content-type (ikut di-sign V4)
+func WithResponseContentType(contentType string) URLOption {
+	return func(opts *urlOptions) {
+		opts.Scheme = cloudStorage.SigningSchemeV4
+		setQueryParameter(opts, "response-content-type", contentType)
+	}
+}
+
+// Override Content-Disposition response lewat query response-content-disposition (ikut di-sign V4)
+func WithResponseDisposition(dispositionType string, filename string) URLOption {
+	return func(opts *urlOptions) {
+		opts.Scheme = cloudStorage.SigningSchemeV4
+		setQueryParameter(opts, "response-content-disposition", utils.ContentDisposition(dispositionType, filename))
+	}
+}
+
+// Pin signed url ke generation tertentu (versi lama di bucket dengan versioning)
+func WithGeneration(generation int64) URLOption {
+	return func(opts *urlOptions) {
+		opts.Scheme = cloudStorage.SigningSchemeV4
+		setQueryParameter(opts, "generation", strconv.FormatInt(generation, 10))
+	}
+}
+
+// Sign thumbnail ukuran size dari object, bukan object aslinya
+func WithVariant(size int) URLOption {
+	return func(opts *urlOptions) {
+		opts.Variant = size
+	}
+}
+
+func setQueryParameter(opts *urlOptions, key string, value string) {
+	if opts.QueryParameters == nil {
+		opts.QueryParameters = url.Values{}
+	}
+	opts.QueryParameters.Set(key, value)
+}
+
+// Baca query disposition (inline/attachment), name dan content_type dari request
+// lalu ubah jadi URLOption. name default ke nama object terakhir di path.
+func responseOverrideOptions(c *gin.Context, filename string, defaultDisposition string) ([]URLOption, error) {
+	disposition, name, err := dispositionFromQuery(c, filename, defaultDisposition)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []URLOption{WithResponseDisposition(disposition, name)}
+	if contentType := c.Query("content_type"); contentType != "" {
+		opts = append(opts, WithResponseContentType(contentType))
+	}
+
+	return opts, nil
+}
+
+// Baca query ?generation=N, kosong berarti versi live
+func generationOptions(c *gin.Context) ([]URLOption, error) {
+	v := c.Query("generation")
+	if v == "" {
+		return nil, nil
+	}
+
+	generation, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || generation <= 0 {
+		return nil, errors.New("invalid generation")
+	}
+	return []URLOption{WithGeneration(generation)}, nil
+}
+
+// Baca query ?variant=N (ukuran thumbnail), kosong berarti object aslinya
+func variantOptions(c *gin.Context) ([]URLOption, error) {
+	v := c.Query("variant")
+	if v == "" {
+		return nil, nil
+	}
+
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		return nil, errors.New("invalid variant")
+	}
+	return []URLOption{WithVariant(size)}, nil
+}
+
+// Ambil dan validasi wildcard *path dari URL, langsung balas 400 kalau tidak valid
+func objectPathParam(c *gin.Context) (string, bool) {
+	objectPath, err := utils.NormalizeObjectPath(c.Param("path"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+
+	// Object karantina tidak boleh di-download sampai direview
+	if strings.HasPrefix(objectPath, quarantinePrefix) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errQuarantined.Error()})
+		return "", false
+	}
+	return objectPath, true
+}
+
+func dispositionFromQuery(c *gin.Context, filename string, defaultDisposition string) (string, string, error) {
+	disposition := c.DefaultQuery("disposition", defaultDisposition)
+	if disposition != "inline" && disposition != "attachment" {
+		return "", "", errors.New("disposition must be inline or attachment")
+	}
+
+	return disposition, c.DefaultQuery("name", path.Base(filename)), nil
+}
+
+func formatLengthRange(size int64) string {
+	return fmt.Sprintf("%d,%d", size, size)
+}
+
 // Generate Signed URL menggunakan metode yang sama seperti di ethica-be
-func GenerateURL(filename string, ttlSecond int, client *storage.Client) (string, string, error) {
+func GenerateURL(filename string, ttlSecond int, client *storage.Client, opts ...URLOption) (string, string, error) {
 	// Konversi ttlSecond ke time.Duration
 	expirationDuration := time.Duration(ttlSecond) * time.Second
 
 	// Set the expiration time to just a few seconds in the future
 	expirationTime := time.Now().Add(expirationDuration)
 
+	filename, err := utils.NormalizeObjectPath(filename)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.HasPrefix(filename, quarantinePrefix) {
+		return "", "", errQuarantined
+	}
+
 	bucketName := os.Getenv("BUCKET_NAME")
 
-	signedUrl, err := cloudStorage.SignedURL(bucketName, filename, &cloudStorage.SignedURLOptions{
+	signOpts := &urlOptions{SignedURLOptions: cloudStorage.SignedURLOptions{
 		GoogleAccessID: os.Getenv("FIREBASE_CLIENT_EMAIL"),
-		PrivateKey:     []byte(strings.Replace(string(os.Getenv("FIREBASE_PRIVATE_KEY")), "\\n", "\n", -1)),
+		PrivateKey:     signingPrivateKey(),
 		Method:         "GET",
 		Expires:        expirationTime,
-	})
+	}}
+	for _, opt := range opts {
+		opt(signOpts)
+	}
+
+	if signOpts.Variant > 0 {
+		// Thumbnail punya generation sendiri, tidak bisa dipin ke generation object asli
+		if signOpts.QueryParameters.Get("generation") != "" {
+			return "", "", errors.New("variant cannot be combined with generation")
+		}
+		filename = thumbnailName(filename, signOpts.Variant)
+	}
+
+	// File yang belum di-scan (atau infected) tidak boleh bisa didownload
+	if signOpts.Method == http.MethodGet && scanningEnabled() {
+		if err := checkObjectScanned(client, filename, signOpts.QueryParameters.Get("generation")); err != nil {
+			return "", "", err
+		}
+	}
+
+	signedUrl, err := cloudStorage.SignedURL(bucketName, filename, &signOpts.SignedURLOptions)
 
 	if err != nil {
 		return "", "", err
 	}
 
-	rawURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, filename)
+	rawURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, escapeObjectPath(filename))
+	if generation := signOpts.QueryParameters.Get("generation"); generation != "" {
+		rawURL += "?generation=" + generation
+	}
 
 	return signedUrl, rawURL, err
 }
 
+// Cek verdict scan object (atau generation tertentu) sebelum URL-nya di-sign
+func checkObjectScanned(client *storage.Client, filename string, generation string) error {
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return err
+	}
+
+	obj := bucket.Object(filename)
+	if generation != "" {
+		n, err := strconv.ParseInt(generation, 10, 64)
+		if err != nil {
+			return err
+		}
+		obj = obj.Generation(n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+	return checkScanned(attrs.Metadata)
+}
+
+// Status HTTP untuk error dari GenerateURL
+func urlErrorStatus(err error) int {
+	if errors.Is(err, errNotScanned) || errors.Is(err, errQuarantined) {
+		return http.StatusForbidden
+	}
+	return storageErrorStatus(err)
+}
+
+// Escape tiap segmen path object untuk dipakai di URL, slash tetap dipertahankan
+func escapeObjectPath(objectPath string) string {
+	segments := strings.Split(objectPath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Private key service account untuk signing (newline di env ditulis sebagai literal \n)
+func signingPrivateKey() []byte {
+	return []byte(strings.Replace(string(os.Getenv("FIREBASE_PRIVATE_KEY")), "\\n", "\n", -1))
+}
+
 func addDocWithoutID(ctx context.Context, client *firestore.Client, data string) error {
 	_, _, err := client.Collection("tes").Add(ctx, map[string]interface{}{
 		"timestamp": time.Now(),
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Pasang precondition ke handle, kosong berarti tanpa syarat
+func withConditions(obj *cloudStorage.ObjectHandle, conds cloudStorage.Conditions) *cloudStorage.ObjectHandle {
+	if conds == (cloudStorage.Conditions{}) {
+		return obj
+	}
+	return obj.If(conds)
+}
+
+// Handler DELETE /objects/*path
+func deleteObjectHandler(c *gin.Context) {
+	objectPath, action, ok := objectActionParam(c)
+	if !ok {
+		return
+	}
+	if action != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown object action"})
+		return
+	}
+
+	// Blob dedupe bisa dipakai banyak file, hanya boleh hilang lewat release ref
+	if isBlobObject(objectPath) {
+		c.JSON(http.StatusConflict, gin.H{"error": errBlobManaged.Error()})
+		return
+	}
+
+	conds, err := objectConditions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := withConditions(bucket.Object(objectPath), conds).Delete(c); err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	deleteDerivedObjects(c, bucket, objectPath)
+
+	c.Status(http.StatusNoContent)
+}
+
+// Handler POST /objects/*path?action={copy,move,rename,restore,scan}
+func objectPostHandler(c *gin.Context) {
+	objectPath, action, ok := objectActionParam(c)
+	if !ok {
+		return
+	}
+
+	switch action {
+	case "copy":
+		copyObjectHandler(c, objectPath, false)
+	case "move", "rename":
+		copyObjectHandler(c, objectPath, true)
+	case "restore":
+		restoreObjectVersionHandler(c, objectPath)
+	case "scan":
+		scanObjectHandler(c, objectPath)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown object action"})
+	}
+}
+
+// Copy server-side, dan kalau move juga hapus source setelahnya.
+// Precondition di query berlaku untuk object source. Destination hanya boleh
+// ditimpa kalau overwrite=true. Source di-pin ke generation yang dibaca di
+// awal, jadi kalau ada device lain yang mengubah source di tengah jalan,
+// hasilnya 412 dan copy di-rollback. Kalau move menimpa destination yang sudah
+// ada, rollback tidak mungkin tanpa kehilangan isi lama, jadi copy dibiarkan
+// dan client diberi tahu.
+func copyObjectHandler(c *gin.Context, objectPath string, move bool) {
+	conds, err := objectConditions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req types.ObjectCopyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	destination, err := utils.NormalizeObjectPath(req.Destination)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid destination"})
+		return
+	}
+	if destination == objectPath {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "destination must differ from source"})
+		return
+	}
+	if isReservedPath(destination) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errReservedPath.Error()})
+		return
+	}
+	if move && isBlobObject(objectPath) {
+		c.JSON(http.StatusConflict, gin.H{"error": errBlobManaged.Error()})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	src := bucket.Object(objectPath)
+	srcAttrs, err := withConditions(src, conds).Attrs(c)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Catat generation destination yang ditimpa, copy di-pin ke generation itu
+	// supaya tidak menimpa versi lain yang ditulis di tengah jalan
+	var replacedGeneration int64
+	dst := bucket.Object(destination)
+	if req.Overwrite {
+		existing, err := dst.Attrs(c)
+		switch {
+		case errors.Is(err, cloudStorage.ErrObjectNotExist):
+			dst = dst.If(cloudStorage.Conditions{DoesNotExist: true})
+		case err != nil:
+			c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		default:
+			replacedGeneration = existing.Generation
+			dst = dst.If(cloudStorage.Conditions{GenerationMatch: existing.Generation})
+		}
+	} else {
+		dst = dst.If(cloudStorage.Conditions{DoesNotExist: true})
+	}
+
+	dstAttrs, err := dst.CopierFrom(src.Generation(srcAttrs.Generation)).Run(c)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if move {
+		err := src.If(cloudStorage.Conditions{
+			GenerationMatch:     srcAttrs.Generation,
+			MetagenerationMatch: srcAttrs.Metageneration,
+		}).Delete(c)
+		if err != nil && replacedGeneration != 0 {
+			// Menghapus copy berarti isi destination lama ikut hilang, jadi copy
+			// dibiarkan dan source tetap ada
+			c.JSON(storageErrorStatus(err), gin.H{
+				"error":               err.Error(),
+				"destination":         objectMetaResponse(dstAttrs),
+				"replaced_generation": replacedGeneration,
+				"source_deleted":      false,
+			})
+			return
+		}
+		if err != nil {
+			// Source berubah atau gagal dihapus, batalkan copy supaya tidak ada duplikat
+			bucket.Object(destination).If(cloudStorage.Conditions{
+				GenerationMatch: dstAttrs.Generation,
+			}).Delete(context.Background())
+			c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Thumbnail dan cache /img ikut nama object, jadi turunan milik destination
+	// lama dibuang, thumbnail source disalin, dan kalau move turunan source dihapus
+	if replacedGeneration != 0 {
+		deleteDerivedObjects(c, bucket, destination)
+	}
+	if attrs := copyThumbnails(c, bucket, objectPath, destination, dstAttrs.Metadata); attrs != nil {
+		dstAttrs = attrs
+	}
+	if move {
+		deleteDerivedObjects(c, bucket, objectPath)
+	}
+
+	c.JSON(http.StatusOK, objectMetaResponse(dstAttrs))
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	defaultListPageSize = 100
+	maxListPageSize     = 1000
+)
+
+type objectSummary struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Handler untuk list isi bucket per halaman.
+// Dengan delimiter "/", object di bawah sub-folder dikembalikan sebagai prefixes.
+func listObjectsHandler(c *gin.Context) {
+	prefix := c.Query("prefix")
+	if prefix != "" {
+		// Prefix boleh diakhiri slash (folder), sisanya harus path yang valid
+		if _, err := utils.NormalizeObjectPath(strings.TrimSuffix(prefix, "/")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prefix"})
+			return
+		}
+	}
+
+	delimiter := c.Query("delimiter")
+	if delimiter != "" && delimiter != "/" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delimiter must be empty or /"})
+		return
+	}
+
+	pageSize, err := pageSizeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := &cloudStorage.Query{Prefix: prefix, Delimiter: delimiter}
+	if err := query.SetAttrSelection([]string{"Name", "Size", "ContentType", "Updated"}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var page []*cloudStorage.ObjectAttrs
+	nextPageToken, err := iterator.NewPager(bucket.Objects(c, query), pageSize, c.Query("pageToken")).NextPage(&page)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	objects := []objectSummary{}
+	prefixes := []string{}
+	for _, attrs := range page {
+		// Object internal (chunk upload, blob, karantina, thumbnail dan cache /img)
+		// tidak ikut ditampilkan
+		if isReservedPath(attrs.Name) || isReservedPath(strings.TrimSuffix(attrs.Prefix, "/")) {
+			continue
+		}
+
+		if attrs.Prefix != "" {
+			prefixes = append(prefixes, attrs.Prefix)
+			continue
+		}
+
+		objects = append(objects, objectSummary{
+			Name:        attrs.Name,
+			Size:        attrs.Size,
+			ContentType: attrs.ContentType,
+			Updated:     attrs.Updated,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"objects":         objects,
+		"prefixes":        prefixes,
+		"next_page_token": nextPageToken,
+	})
+}
+
+// Baca query pageSize, default 100 dan maksimal 1000
+func pageSizeQuery(c *gin.Context) (int, error) {
+	v := c.Query("pageSize")
+	if v == "" {
+		return defaultListPageSize, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 || n > maxListPageSize {
+		return 0, errors.New("pageSize must be between 1 and 1000")
+	}
+	return n, nil
+}
+
+// Action object lewat query ?action=, mis. GET /objects/a/b.png?action=meta
+var objectActions = map[string]bool{
+	"meta":     true,
+	"copy":     true,
+	"move":     true,
+	"rename":   true,
+	"versions": true,
+	"restore":  true,
+	"scan":     true,
+}
+
+// Ambil path object dari wildcard *path dan action dari query ?action=, lalu
+// validasi keduanya. Path selalu dipakai utuh, jadi object bernama mis.
+// a/meta atau a/copy tetap bisa dialamatkan. Balas 400 kalau path invalid
+// dan 404 kalau action tidak dikenal.
+func objectActionParam(c *gin.Context) (string, string, bool) {
+	action := c.Query("action")
+	if action != "" && !objectActions[action] {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown object action"})
+		return "", "", false
+	}
+
+	objectPath, err := utils.NormalizeObjectPath(c.Param("path"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", "", false
+	}
+	return objectPath, action, true
+}
+
+// Baca precondition ifGenerationMatch / ifMetagenerationMatch dari query
+func objectConditions(c *gin.Context) (cloudStorage.Conditions, error) {
+	var conds cloudStorage.Conditions
+
+	if v := c.Query("ifGenerationMatch"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return conds, errors.New("invalid ifGenerationMatch")
+		}
+		conds.GenerationMatch = n
+		// ifGenerationMatch=0 artinya object belum boleh ada
+		conds.DoesNotExist = n == 0
+	}
+
+	if v := c.Query("ifMetagenerationMatch"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return conds, errors.New("invalid ifMetagenerationMatch")
+		}
+		conds.MetagenerationMatch = n
+	}
+
+	return conds, nil
+}
+
+// Handler GET /objects/*path?action={meta,versions}
+func objectGetHandler(c *gin.Context) {
+	objectPath, action, ok := objectActionParam(c)
+	if !ok {
+		return
+	}
+
+	switch action {
+	case "meta":
+		objectMetaHandler(c, objectPath)
+	case "versions":
+		listObjectVersionsHandler(c, objectPath)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown object action"})
+	}
+}
+
+// Handler PATCH /objects/*path?action=meta
+func objectPatchHandler(c *gin.Context) {
+	objectPath, action, ok := objectActionParam(c)
+	if !ok {
+		return
+	}
+
+	switch action {
+	case "meta":
+		patchObjectMetaHandler(c, objectPath)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown object action"})
+	}
+}
+
+// Detail object dalam bentuk JSON
+func objectMetaHandler(c *gin.Context, objectPath string) {
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attrs, err := bucket.Object(objectPath).Attrs(c)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, objectMetaResponse(attrs))
+}
+
+func objectMetaResponse(attrs *cloudStorage.ObjectAttrs) gin.H {
+	metadata := attrs.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	return gin.H{
+		"name":           attrs.Name,
+		"size":           attrs.Size,
+		"content_type":   attrs.ContentType,
+		"md5":            encodeMD5(attrs.MD5),
+		"crc32c":         encodeCRC32C(attrs.CRC32C),
+		"etag":           attrs.Etag,
+		"generation":     attrs.Generation,
+		"metageneration": attrs.Metageneration,
+		"storage_class":  attrs.StorageClass,
+		"created":        attrs.Created,
+		"updated":        attrs.Updated,
+		"metadata":       metadata,
+	}
+}
+
+// Handler HEAD /objects/*path, fakta object dikirim lewat header saja
+func headObjectHandler(c *gin.Context) {
+	objectPath, _, ok := objectActionParam(c)
+	if !ok {
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	attrs, err := bucket.Object(objectPath).Attrs(c)
+	if err != nil {
+		c.AbortWithStatus(storageErrorStatus(err))
+		return
+	}
+
+	c.Header("Content-Length", strconv.FormatInt(attrs.Size, 10))
+	c.Header("Content-Type", attrs.ContentType)
+	c.Header("ETag", `"`+attrs.Etag+`"`)
+	c.Header("Last-Modified", attrs.Updated.UTC().Format(http.TimeFormat))
+	c.Header("X-Goog-Generation", strconv.FormatInt(attrs.Generation, 10))
+	c.Header("X-Goog-Metageneration", strconv.FormatInt(attrs.Metageneration, 10))
+	c.Header("X-Goog-Storage-Class", attrs.StorageClass)
+
+	hashes := "crc32c=" + encodeCRC32C(attrs.CRC32C)
+	if len(attrs.MD5) > 0 {
+		hashes += ",md5=" + encodeMD5(attrs.MD5)
+	}
+	c.Header("X-Goog-Hash", hashes)
+
+	for key, value := range attrs.Metadata {
+		c.Header("X-Goog-Meta-"+key, value)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Update custom metadata. Key di body di-merge ke metadata yang ada,
+// body {"metadata": {}} menghapus semua. ifMetagenerationMatch wajib supaya
+// perubahan dari dua client tidak saling timpa diam-diam.
+func patchObjectMetaHandler(c *gin.Context, objectPath string) {
+	conds, err := objectConditions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if conds.MetagenerationMatch == 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "ifMetagenerationMatch is required"})
+		return
+	}
+
+	var req types.ObjectMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Metadata == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metadata is required"})
+		return
+	}
+	for key := range req.Metadata {
+		if isScanMetadataKey(key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errScanMetadata.Error()})
+			return
+		}
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Hasil scan ikut terhapus kalau semua metadata dihapus, simpan dulu untuk dipasang lagi
+	var scanMetadata map[string]string
+	if len(req.Metadata) == 0 {
+		current, err := bucket.Object(objectPath).If(conds).Attrs(c)
+		if err != nil {
+			c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		scanMetadata = scanMetadataOf(current.Metadata)
+	}
+
+	attrs, err := bucket.Object(objectPath).If(conds).Update(c, cloudStorage.ObjectAttrsToUpdate{
+		Metadata: req.Metadata,
+	})
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(scanMetadata) > 0 {
+		restoreConds := cloudStorage.Conditions{MetagenerationMatch: attrs.Metageneration}
+		attrs, err = bucket.Object(objectPath).If(restoreConds).Update(c, cloudStorage.ObjectAttrsToUpdate{
+			Metadata: scanMetadata,
+		})
+		if err != nil {
+			c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, objectMetaResponse(attrs))
+}
+
+func encodeMD5(sum []byte) string {
+	if len(sum) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(sum)
+}
+
+// CRC32C dalam format GCS: base64 dari 4 byte big-endian
+func encodeCRC32C(crc uint32) string {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, crc)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// Map error dari GCS ke status HTTP yang relevan untuk client
+func storageErrorStatus(err error) int {
+	if errors.Is(err, cloudStorage.ErrObjectNotExist) || errors.Is(err, cloudStorage.ErrBucketNotExist) {
+		return http.StatusNotFound
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed:
+			return apiErr.Code
+		}
+	}
+	return http.StatusInternalServerError
+}
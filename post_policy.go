@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler untuk generate signed POST policy V4.
+// Policy mengunci ukuran file, Content-Type, dan prefix nama object, jadi form
+// HTML bisa upload langsung ke bucket tanpa bisa keluar dari prefix tersebut.
+func uploadPolicyHandler(c *gin.Context) {
+	var req types.PostPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix, err := utils.NormalizeObjectPath(strings.TrimSuffix(req.Prefix, "/"))
+	if err == nil && isReservedPath(prefix) {
+		err = errReservedPath
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prefix"})
+		return
+	}
+
+	fileType, err := utils.LookupType(req.ContentType)
+	if err != nil || !allowedTypes("UPLOAD_POLICY").Allows(fileType.MimeType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": errUnsupportedFormat.Error()})
+		return
+	}
+
+	maxSize := envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize)
+	if req.MaxSize <= 0 || req.MaxSize > maxSize {
+		req.MaxSize = maxSize
+	}
+
+	ttl := req.TTLSecond
+	if ttl <= 0 {
+		ttl = defaultUploadURLTTL
+	}
+	if ttl > maxUploadURLTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttl_second exceeds 7 days"})
+		return
+	}
+	expires := time.Now().Add(time.Duration(ttl) * time.Second)
+
+	objectName, err := newObjectName(prefix+"/", uploadTenant(c), fileType.Ext())
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	policy, err := cloudStorage.GenerateSignedPostPolicyV4(os.Getenv("BUCKET_NAME"), objectName, &cloudStorage.PostPolicyV4Options{
+		GoogleAccessID: os.Getenv("FIREBASE_CLIENT_EMAIL"),
+		PrivateKey:     signingPrivateKey(),
+		Expires:        expires,
+		Fields: &cloudStorage.PolicyV4Fields{
+			ContentType:         req.ContentType,
+			StatusCodeOnSuccess: http.StatusCreated,
+		},
+		Conditions: []cloudStorage.PostPolicyV4Condition{
+			cloudStorage.ConditionContentLengthRange(1, uint64(req.MaxSize)),
+			cloudStorage.ConditionStartsWith("$key", prefix+"/"),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object_name": objectName,
+		"url":         policy.URL,
+		// Semua field ini wajib ikut di form, field "file" harus paling akhir
+		"fields":     policy.Fields,
+		"expires_at": expires.UTC(),
+	})
+}
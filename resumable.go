@@ -0,0 +1,721 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	"cloud.google.com/go/firestore"
+	cloudStorage "cloud.google.com/go/storage"
+	"firebase.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Collection Firestore untuk state upload session
+const uploadSessionCollection = "upload_sessions"
+
+// Prefix object sementara untuk chunk yang sudah di-commit
+const chunkPrefix = ".uploads/"
+
+// Batas source per compose dari GCS, dan batas total komponen composite object
+const (
+	maxComposeSources = 32
+	maxChunkCount     = 1024
+)
+
+var (
+	errSessionNotFound  = errors.New("upload session not found")
+	errSessionCompleted = errors.New("upload session already completed")
+	errSessionExpired   = errors.New("upload session expired")
+	errOffsetMismatch   = errors.New("chunk does not start at committed offset")
+	errUploadIncomplete = errors.New("upload is not complete yet")
+	errTooManyChunks    = errors.New("too many chunks, use larger chunk size")
+	errChecksumMismatch = errors.New("chunk checksum mismatch")
+)
+
+type uploadSession struct {
+	Size        int64    `firestore:"size"`
+	Offset      int64    `firestore:"offset"`
+	Chunks      []string `firestore:"chunks"`
+	ObjectName  string   `firestore:"object_name"`
+	ContentType string   `firestore:"content_type"`
+	Metadata    string   `firestore:"metadata"`
+	Tenant      string   `firestore:"tenant"`
+	Completed   bool     `firestore:"completed"`
+	// Object final dikarantina karena tidak cocok dengan deklarasi client
+	Quarantined bool                `firestore:"quarantined"`
+	Mismatch    *utils.TypeMismatch `firestore:"mismatch"`
+	// Hasil compose terdeteksi malware, ObjectName menunjuk object karantina
+	Infected  bool      `firestore:"infected"`
+	CreatedAt time.Time `firestore:"created_at"`
+	UpdatedAt time.Time `firestore:"updated_at"`
+	// Untuk TTL policy Firestore, dokumen session dihapus otomatis setelah ini
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// Catat perubahan session dan perpanjang umurnya
+func (s *uploadSession) touch() {
+	s.UpdatedAt = time.Now()
+	s.ExpiresAt = s.UpdatedAt.Add(uploadSessionTTL())
+}
+
+// Session yang belum selesai dan tidak menerima chunk selama
+// UPLOAD_SESSION_TTL tidak bisa dilanjutkan lagi
+func (s *uploadSession) expired() bool {
+	return !s.Completed && time.Since(s.UpdatedAt) > uploadSessionTTL()
+}
+
+// Handler untuk membuat upload session baru
+func createUploadSessionHandler(c *gin.Context) {
+	var req types.ResumableSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	maxSize := envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize)
+	if req.Size <= 0 || req.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be between 1 and max upload size"})
+		return
+	}
+
+	id, err := createUploadSession(c, firestoreClient, req.Size, uploadTenant(c), "")
+	if err != nil {
+		respondSessionError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"session_id": id,
+		"size":       req.Size,
+		"offset":     0,
+	})
+}
+
+// Handler untuk cek offset yang sudah di-commit
+func uploadSessionStatusHandler(c *gin.Context) {
+	session, err := getUploadSession(c, firestoreClient, c.Param("id"))
+	if err != nil {
+		respondSessionError(c, err, nil)
+		return
+	}
+
+	setCommittedRange(c, session.Offset)
+	c.JSON(http.StatusOK, gin.H{
+		"size":        session.Size,
+		"offset":      session.Offset,
+		"completed":   session.Completed,
+		"object_name": session.ObjectName,
+		"infected":    session.Infected,
+	})
+}
+
+// Handler untuk upload satu chunk dengan header Content-Range.
+// Chunk harus mulai persis di offset yang sudah di-commit, jadi client yang
+// putus koneksi cukup cek offset lalu lanjut dari situ.
+func uploadChunkHandler(c *gin.Context) {
+	start, end, total, err := parseContentRange(c.GetHeader("Content-Range"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	if c.Request.ContentLength >= 0 && c.Request.ContentLength != end-start+1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errBadChunk.Error()})
+		return
+	}
+
+	session, err := appendChunk(c, client, firestoreClient, id, c.Request.Body, start, end-start+1, total, nil)
+	if err != nil {
+		respondSessionError(c, err, session)
+		return
+	}
+
+	setCommittedRange(c, session.Offset)
+	c.JSON(http.StatusOK, gin.H{
+		"size":   session.Size,
+		"offset": session.Offset,
+	})
+}
+
+// Handler untuk menggabungkan semua chunk jadi object final
+func completeUploadSessionHandler(c *gin.Context) {
+	session, err := completeUploadSession(c, client, firestoreClient, c.Param("id"), "RESUMABLE")
+	if err != nil {
+		respondSessionError(c, err, session)
+		return
+	}
+	if session.Quarantined {
+		respondQuarantined(c, session.ObjectName, session.Mismatch)
+		return
+	}
+
+	signedURL, rawURL, err := GenerateURL(session.ObjectName, 30, client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object_name":  session.ObjectName,
+		"content_type": session.ContentType,
+		"size":         session.Size,
+		"signed_url":   signedURL,
+		"raw_url":      rawURL,
+	})
+}
+
+func respondSessionError(c *gin.Context, err error, session *uploadSession) {
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errOffsetMismatch), errors.Is(err, errUploadIncomplete):
+		// Kasih tahu offset terakhir supaya client bisa resume
+		body := gin.H{"error": err.Error()}
+		if session != nil {
+			setCommittedRange(c, session.Offset)
+			body["offset"] = session.Offset
+		}
+		c.JSON(http.StatusConflict, body)
+	case errors.Is(err, errSessionCompleted), errors.Is(err, errSessionExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, errUploadTooLarge), errors.Is(err, errTooManyChunks):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, errUnsupportedFormat):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.As(err, new(*utils.TypeMismatch)), errors.Is(err, utils.ErrInvalidImage):
+		respondUploadError(c, err)
+	case errors.Is(err, errBadChunk), errors.Is(err, errChecksumMismatch), errors.Is(err, utils.ErrInvalidObjectPath):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errNameCollision):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errInfected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, errScanUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrClamdSizeLimit):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// Header Range dengan format yang sama seperti resumable upload GCS
+func setCommittedRange(c *gin.Context, offset int64) {
+	if offset > 0 {
+		c.Header("Range", fmt.Sprintf("bytes=0-%d", offset-1))
+	}
+}
+
+var errBadChunk = errors.New("invalid Content-Range or chunk body")
+
+// Parse "bytes start-end/total", total boleh "*"
+func parseContentRange(header string) (int64, int64, int64, error) {
+	spec := strings.TrimPrefix(header, "bytes ")
+	if spec == header {
+		return 0, 0, 0, errBadChunk
+	}
+
+	slash := strings.IndexByte(spec, '/')
+	dash := strings.IndexByte(spec, '-')
+	if slash < 0 || dash < 0 || dash > slash {
+		return 0, 0, 0, errBadChunk
+	}
+
+	start, err := strconv.ParseInt(spec[:dash], 10, 64)
+	if err != nil {
+		return 0, 0, 0, errBadChunk
+	}
+	end, err := strconv.ParseInt(spec[dash+1:slash], 10, 64)
+	if err != nil || end < start {
+		return 0, 0, 0, errBadChunk
+	}
+
+	total := int64(-1)
+	if spec[slash+1:] != "*" {
+		total, err = strconv.ParseInt(spec[slash+1:], 10, 64)
+		if err != nil || end >= total {
+			return 0, 0, 0, errBadChunk
+		}
+	}
+
+	return start, end, total, nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func createUploadSession(ctx context.Context, fs *firestore.Client, size int64, tenant string, metadata string) (string, error) {
+	// Pastikan tenant valid untuk template nama sekarang, bukan baru saat complete
+	if _, err := newObjectName("", tenant, ""); err != nil {
+		return "", err
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	session := &uploadSession{
+		Size:      size,
+		Chunks:    []string{},
+		Metadata:  metadata,
+		Tenant:    tenant,
+		CreatedAt: time.Now(),
+	}
+	session.touch()
+	_, err = fs.Collection(uploadSessionCollection).Doc(id).Create(ctx, session)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func getUploadSession(ctx context.Context, fs *firestore.Client, id string) (*uploadSession, error) {
+	doc, err := fs.Collection(uploadSessionCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session uploadSession
+	if err := doc.DataTo(&session); err != nil {
+		return nil, err
+	}
+	if session.expired() {
+		return &session, errSessionExpired
+	}
+	return &session, nil
+}
+
+// Session kedaluwarsa dibersihkan begitu disentuh lagi. Yang tidak pernah
+// disentuh lagi dibersihkan lifecycle rule bucket dan TTL policy Firestore
+// (lihat readme).
+func expireUploadSession(client *storage.Client, fs *firestore.Client, id string, session *uploadSession) {
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return
+	}
+	deleteUploadSession(context.Background(), bucket, fs, id, session)
+}
+
+// Checksum yang diharapkan untuk satu chunk, diverifikasi sebelum commit
+type chunkChecksum struct {
+	hash hash.Hash
+	sum  []byte
+}
+
+// Tulis body chunk ke object sementara lalu commit offset di Firestore.
+// length -1 berarti panjang tidak diketahui, body dibaca sampai EOF dengan
+// batas sisa ukuran session. Kalau ada chunk lain yang commit duluan di
+// offset yang sama, object sementara dihapus dan errOffsetMismatch dikembalikan.
+func appendChunk(ctx context.Context, client *storage.Client, fs *firestore.Client, id string, body io.Reader, start, length, total int64, checksum *chunkChecksum) (*uploadSession, error) {
+	session, err := getUploadSession(ctx, fs, id)
+	if errors.Is(err, errSessionExpired) {
+		expireUploadSession(client, fs, id, session)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.Completed {
+		return session, errSessionCompleted
+	}
+	if total >= 0 && total != session.Size {
+		return session, errBadChunk
+	}
+	if start != session.Offset {
+		return session, errOffsetMismatch
+	}
+	if start+length > session.Size {
+		return session, errUploadTooLarge
+	}
+	if length == 0 {
+		return session, nil
+	}
+	if len(session.Chunks) >= maxChunkCount {
+		return session, errTooManyChunks
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return session, err
+	}
+
+	// Suffix random supaya dua request di offset yang sama tidak saling timpa
+	suffix, err := newSessionID()
+	if err != nil {
+		return session, err
+	}
+	chunkName := fmt.Sprintf("%s%s/%020d-%s", chunkPrefix, id, start, suffix)
+	n, err := writeChunk(ctx, bucket, chunkName, body, length, session.Size-start, checksum)
+	if err != nil {
+		return session, err
+	}
+
+	ref := fs.Collection(uploadSessionCollection).Doc(id)
+	err = fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(session); err != nil {
+			return err
+		}
+		if session.Completed {
+			return errSessionCompleted
+		}
+		if session.Offset != start {
+			return errOffsetMismatch
+		}
+
+		session.Offset = start + n
+		session.Chunks = append(session.Chunks, chunkName)
+		session.touch()
+		return tx.Set(ref, session)
+	})
+	if err != nil {
+		// Chunk kalah race atau gagal commit, jangan tinggalkan sampah
+		if errors.Is(err, errOffsetMismatch) || errors.Is(err, errSessionCompleted) {
+			bucket.Object(chunkName).Delete(context.Background())
+		}
+		return session, err
+	}
+
+	return session, nil
+}
+
+// Stream body ke object sementara. Kalau length diketahui harus pas, kalau
+// tidak (-1) cukup tidak melebihi limit. Selain itu, atau kalau checksum tidak
+// cocok, upload di-abort dan object tidak pernah di-commit.
+func writeChunk(ctx context.Context, bucket *cloudStorage.BucketHandle, name string, body io.Reader, length, limit int64, checksum *chunkChecksum) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := bucket.Object(name).NewWriter(ctx)
+	w.ContentType = "application/octet-stream"
+
+	var dst io.Writer = w
+	if checksum != nil {
+		dst = io.MultiWriter(w, checksum.hash)
+	}
+
+	if length >= 0 {
+		limit = length
+	}
+
+	abort := func(err error) (int64, error) {
+		cancel()
+		w.Close()
+		return 0, err
+	}
+
+	n, err := io.Copy(dst, io.LimitReader(body, limit+1))
+	if err != nil {
+		return abort(err)
+	}
+	if n > limit {
+		return abort(errUploadTooLarge)
+	}
+	if length >= 0 && n != length {
+		return abort(errBadChunk)
+	}
+	if checksum != nil && !bytes.Equal(checksum.hash.Sum(nil), checksum.sum) {
+		return abort(errChecksumMismatch)
+	}
+
+	return n, w.Close()
+}
+
+// Gabungkan semua chunk jadi object final dengan nama baru dari newObjectName.
+// Mime type dideteksi dari byte awal chunk pertama, harus ada di allowlist route
+// dan dicek terhadap filename/filetype di Upload-Metadata (tus). Gambar
+// ditulis ulang lewat sanitizeImage dan dibuatkan thumbnail, sama seperti
+// upload biasa.
+func completeUploadSession(ctx context.Context, client *storage.Client, fs *firestore.Client, id string, route string) (*uploadSession, error) {
+	session, err := getUploadSession(ctx, fs, id)
+	if errors.Is(err, errSessionExpired) {
+		expireUploadSession(client, fs, id, session)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.Completed && session.Infected {
+		return session, errInfected
+	}
+	if session.Completed {
+		return session, nil
+	}
+	if session.Offset != session.Size {
+		return session, errUploadIncomplete
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return session, err
+	}
+
+	r, err := bucket.Object(session.Chunks[0]).NewRangeReader(ctx, 0, utils.SniffLen)
+	if err != nil {
+		return session, err
+	}
+	head, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return session, err
+	}
+
+	fileType, err := utils.DetectType(head)
+	if err != nil || !allowedTypes(route).Allows(fileType.MimeType) {
+		// Session sudah penuh tapi isinya tidak didukung, tidak ada gunanya disimpan
+		deleteUploadSession(context.Background(), bucket, fs, id, session)
+		return session, errUnsupportedFormat
+	}
+	contentType := fileType.MimeType
+
+	declaredName := tusMetadataValue(session.Metadata, "filename")
+	declaredType := tusMetadataValue(session.Metadata, "filetype")
+	quarantined, mismatch, err := resolveTypeMismatch(mismatchPolicy(route), declaredName, declaredType, fileType)
+	if err != nil {
+		deleteUploadSession(context.Background(), bucket, fs, id, session)
+		return session, err
+	}
+
+	// File karantina dan, dengan scanning, hasil compose masuk karantina dulu
+	// sampai verdict-nya clean
+	namePrefix := ""
+	var metadata map[string]string
+	if quarantined {
+		namePrefix = quarantinePrefix
+		metadata = quarantineMetadata(nil, mismatch)
+	}
+	scan := scanningEnabled()
+	if scan {
+		namePrefix = quarantinePrefix
+		metadata = mergeMetadata(metadata, map[string]string{scanVerdictKey: verdictPending})
+	}
+
+	// Gambar di-compose ke object sementara dulu, lalu ditulis ulang tanpa
+	// metadata EXIF/XMP ke nama final
+	_, isImage := thumbnailFormat(contentType)
+	strip := isImage && stripMetadataEnabled(route)
+
+	// Object sementara diberi suffix acak per pemanggilan, supaya complete
+	// yang berjalan bersamaan tidak saling menghapus object sementara
+	suffix, err := newSessionID()
+	if err != nil {
+		return session, err
+	}
+	tmpPrefix := chunkPrefix + id + "/compose-" + suffix + "-"
+
+	composed := ""
+	if strip {
+		composed = chunkPrefix + id + "/composed-" + suffix
+		attrs, err := composeObjects(ctx, bucket, bucket.Object(composed), session.Chunks, contentType, nil, tmpPrefix)
+		if err != nil {
+			return session, err
+		}
+		defer bucket.Object(composed).Delete(context.Background())
+		if attrs.Size != session.Size {
+			return session, errors.New("composed object size does not match session size")
+		}
+	}
+
+	var objectName string
+	var attrs *cloudStorage.ObjectAttrs
+	for attempt := 1; ; attempt++ {
+		objectName, err = newObjectName(namePrefix, session.Tenant, fileType.Ext())
+		if err != nil {
+			return session, err
+		}
+
+		// ifGenerationMatch=0, object lain dengan nama sama tidak tertimpa
+		dst := bucket.Object(objectName).If(cloudStorage.Conditions{DoesNotExist: true})
+		if strip {
+			attrs, err = writeSanitizedObject(ctx, bucket, composed, dst, contentType, metadata, exifKeepTags(route), session.Size)
+		} else {
+			attrs, err = composeObjects(ctx, bucket, dst, session.Chunks, contentType, metadata, tmpPrefix)
+		}
+		if err == nil {
+			break
+		}
+		if !isNameCollision(err) {
+			return session, err
+		}
+		if attempt == maxNameAttempts {
+			return session, errNameCollision
+		}
+	}
+	if !strip && attrs.Size != session.Size {
+		bucket.Object(objectName).Delete(context.Background())
+		return session, errors.New("composed object size does not match session size")
+	}
+
+	// Chunk yang infected tetap infected, jadi session tetap ditutup dengan
+	// object karantina-nya supaya retry tidak compose salinan baru lagi
+	var scanErr error
+	if scan {
+		liveName := ""
+		if !quarantined {
+			liveName = liveObjectName(objectName)
+		}
+		attrs, scanErr = releaseQuarantined(ctx, bucket, objectName, liveName, nil)
+		if scanErr != nil && !errors.Is(scanErr, errInfected) {
+			return session, scanErr
+		}
+		if scanErr == nil {
+			objectName = attrs.Name
+		}
+	}
+
+	ref := fs.Collection(uploadSessionCollection).Doc(id)
+	err = fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(session); err != nil {
+			return err
+		}
+		if session.Completed {
+			return errSessionCompleted
+		}
+
+		session.Completed = true
+		session.ObjectName = objectName
+		session.ContentType = contentType
+		session.Quarantined = quarantined
+		session.Mismatch = mismatch
+		session.Infected = scanErr != nil
+		session.touch()
+		return tx.Set(ref, session)
+	})
+	if errors.Is(err, errSessionCompleted) {
+		// Request lain sudah finalize duluan, pakai hasil yang itu
+		bucket.Object(objectName).Delete(context.Background())
+		if session.Infected {
+			return session, errInfected
+		}
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	deleteObjects(context.Background(), bucket, session.Chunks)
+	if scanErr != nil {
+		return session, scanErr
+	}
+	if !quarantined {
+		attachThumbnails(ctx, objectName, contentType, nil, thumbnailSizes(route))
+	}
+	return session, nil
+}
+
+// Baca object src lewat sanitizeImage lalu tulis hasilnya ke dst. Kalau gagal
+// di tengah jalan upload di-abort, dst tidak pernah di-commit.
+func writeSanitizedObject(ctx context.Context, bucket *cloudStorage.BucketHandle, src string, dst *cloudStorage.ObjectHandle, contentType string, metadata map[string]string, keep []string, maxSize int64) (*cloudStorage.ObjectAttrs, error) {
+	r, err := bucket.Object(src).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	body, tags, err := sanitizeImage(r, contentType, keep, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := dst.NewWriter(ctx)
+	w.ContentType = contentType
+	w.Metadata = mergeMetadata(metadata, tags)
+	if _, err := io.Copy(w, body); err != nil {
+		cancel()
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return w.Attrs(), nil
+}
+
+// Hapus chunk dan dokumen session. Object final (kalau ada) tidak disentuh.
+func deleteUploadSession(ctx context.Context, bucket *cloudStorage.BucketHandle, fs *firestore.Client, id string, session *uploadSession) error {
+	deleteObjects(ctx, bucket, session.Chunks)
+	_, err := fs.Collection(uploadSessionCollection).Doc(id).Delete(ctx)
+	return err
+}
+
+// Compose srcs ke dst. GCS cuma terima 32 source per compose, jadi kalau
+// lebih dari itu digabung bertahap lewat object sementara berprefix tmpPrefix.
+func composeObjects(ctx context.Context, bucket *cloudStorage.BucketHandle, dst *cloudStorage.ObjectHandle, srcs []string, contentType string, metadata map[string]string, tmpPrefix string) (*cloudStorage.ObjectAttrs, error) {
+	var intermediates []string
+	defer func() {
+		deleteObjects(context.Background(), bucket, intermediates)
+	}()
+
+	for round := 0; len(srcs) > maxComposeSources; round++ {
+		var next []string
+		for i := 0; i < len(srcs); i += maxComposeSources {
+			j := i + maxComposeSources
+			if j > len(srcs) {
+				j = len(srcs)
+			}
+
+			name := fmt.Sprintf("%s%d-%d", tmpPrefix, round, i/maxComposeSources)
+			if _, err := composeOnce(ctx, bucket, bucket.Object(name), srcs[i:j], "application/octet-stream", nil); err != nil {
+				return nil, err
+			}
+			intermediates = append(intermediates, name)
+			next = append(next, name)
+		}
+		srcs = next
+	}
+
+	return composeOnce(ctx, bucket, dst, srcs, contentType, metadata)
+}
+
+func composeOnce(ctx context.Context, bucket *cloudStorage.BucketHandle, dst *cloudStorage.ObjectHandle, srcs []string, contentType string, metadata map[string]string) (*cloudStorage.ObjectAttrs, error) {
+	handles := make([]*cloudStorage.ObjectHandle, len(srcs))
+	for i, name := range srcs {
+		handles[i] = bucket.Object(name)
+	}
+
+	composer := dst.ComposerFrom(handles...)
+	composer.ContentType = contentType
+	composer.Metadata = metadata
+	return composer.Run(ctx)
+}
+
+// Hapus object best effort, error diabaikan
+func deleteObjects(ctx context.Context, bucket *cloudStorage.BucketHandle, names []string) {
+	for _, name := range names {
+		bucket.Object(name).Delete(ctx)
+	}
+}
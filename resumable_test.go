@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header string
+		start  int64
+		end    int64
+		total  int64
+		ok     bool
+	}{
+		{"bytes 0-99/100", 0, 99, 100, true},
+		{"bytes 100-199/1000", 100, 199, 1000, true},
+		{"bytes 0-0/1", 0, 0, 1, true},
+		{"bytes 0-262143/*", 0, 262143, -1, true},
+		{"", 0, 0, 0, false},
+		{"0-99/100", 0, 0, 0, false},
+		{"items 0-99/100", 0, 0, 0, false},
+		{"bytes 0-99", 0, 0, 0, false},
+		{"bytes */100", 0, 0, 0, false},
+		{"bytes -99/100", 0, 0, 0, false},
+		{"bytes 0-/100", 0, 0, 0, false},
+		{"bytes 99-0/100", 0, 0, 0, false},
+		{"bytes 0-100/100", 0, 0, 0, false},
+		{"bytes 0-99/99", 0, 0, 0, false},
+		{"bytes 0-99/-1", 0, 0, 0, false},
+		{"bytes a-99/100", 0, 0, 0, false},
+		{"bytes 0-99/abc", 0, 0, 0, false},
+		{"bytes 0-99/100/5", 0, 0, 0, false},
+		{"bytes 0/99-100", 0, 0, 0, false},
+		{"bytes 0-99999999999999999999/*", 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			start, end, total, err := parseContentRange(tt.header)
+			if !tt.ok {
+				if !errors.Is(err, errBadChunk) {
+					t.Fatalf("parseContentRange(%q) = %d, %d, %d, %v, want errBadChunk", tt.header, start, end, total, err)
+				}
+				return
+			}
+			if err != nil || start != tt.start || end != tt.end || total != tt.total {
+				t.Fatalf("parseContentRange(%q) = %d, %d, %d, %v, want %d, %d, %d", tt.header, start, end, total, err, tt.start, tt.end, tt.total)
+			}
+		})
+	}
+}
+
+func TestUploadSessionExpired(t *testing.T) {
+	t.Setenv("UPLOAD_SESSION_TTL", "3600")
+
+	tests := []struct {
+		name      string
+		completed bool
+		age       time.Duration
+		expired   bool
+	}{
+		{"fresh", false, time.Minute, false},
+		{"just before ttl", false, 59 * time.Minute, false},
+		{"stale", false, 2 * time.Hour, true},
+		{"completed stays readable", true, 48 * time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &uploadSession{Completed: tt.completed, UpdatedAt: time.Now().Add(-tt.age)}
+			if got := session.expired(); got != tt.expired {
+				t.Fatalf("expired() = %v, want %v", got, tt.expired)
+			}
+		})
+	}
+
+	session := &uploadSession{}
+	session.touch()
+	if got := session.ExpiresAt.Sub(session.UpdatedAt); got != time.Hour {
+		t.Fatalf("touch() set ExpiresAt %s after UpdatedAt, want 1h", got)
+	}
+}
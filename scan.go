@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Key custom metadata hasil scan malware. Hanya server yang boleh mengisi.
+const (
+	scanMetadataPrefix = "scan_"
+	scanVerdictKey     = "scan_verdict"
+	scanSignatureKey   = "scan_signature"
+	scannedAtKey       = "scan_time"
+)
+
+const (
+	verdictPending  = "pending"
+	verdictClean    = "clean"
+	verdictInfected = "infected"
+)
+
+var (
+	errNotScanned      = errors.New("object has not been scanned for malware")
+	errInfected        = errors.New("file is infected")
+	errScanUnavailable = errors.New("malware scanner unavailable")
+	errScanMetadata    = errors.New("scan metadata is managed by the server")
+)
+
+type scanVerdict struct {
+	Verdict   string
+	Signature string
+	ScannedAt time.Time
+}
+
+func (v scanVerdict) metadata() map[string]string {
+	metadata := map[string]string{
+		scanVerdictKey: v.Verdict,
+		scannedAtKey:   v.ScannedAt.UTC().Format(time.RFC3339),
+	}
+	if v.Signature != "" {
+		metadata[scanSignatureKey] = v.Signature
+	}
+	return metadata
+}
+
+// errInfected kalau verdict bukan clean
+func (v scanVerdict) err() error {
+	if v.Verdict == verdictClean {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errInfected, v.Signature)
+}
+
+func isScanMetadataKey(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), scanMetadataPrefix)
+}
+
+// Salin key hasil scan, dipakai object turunan (thumbnail, cache /img) yang
+// isinya berasal dari object yang sudah di-scan
+func scanMetadataOf(metadata map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range metadata {
+		if isScanMetadataKey(k) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Object boleh di-serve kalau scanning mati atau verdict-nya clean
+func checkScanned(metadata map[string]string) error {
+	if !scanningEnabled() || metadata[scanVerdictKey] == verdictClean {
+		return nil
+	}
+	return errNotScanned
+}
+
+// Kirim isi r ke clamd. Scanner yang tidak bisa dihubungi atau membalas aneh
+// jadi errScanUnavailable, client bisa coba lagi nanti.
+func scanContent(ctx context.Context, r io.Reader) (scanVerdict, error) {
+	scanner, err := malwareScanner()
+	if err != nil {
+		return scanVerdict{}, err
+	}
+
+	result, err := scanner.Scan(ctx, r)
+	if errors.Is(err, utils.ErrClamdSizeLimit) || errors.Is(err, context.Canceled) {
+		return scanVerdict{}, err
+	}
+	if err != nil {
+		return scanVerdict{}, fmt.Errorf("%w: %v", errScanUnavailable, err)
+	}
+
+	verdict := scanVerdict{Verdict: verdictClean, ScannedAt: time.Now()}
+	if result.Infected {
+		verdict.Verdict = verdictInfected
+		verdict.Signature = result.Signature
+	}
+	return verdict, nil
+}
+
+// Scan isi object lalu catat verdict-nya di metadata. Kalau clean dan
+// liveName diisi, object dipindah ke liveName. Infected mengembalikan
+// errInfected dan object dibiarkan di tempatnya. src nil berarti isi dibaca
+// dari bucket.
+func scanStoredObject(ctx context.Context, bucket *cloudStorage.BucketHandle, objectName string, liveName string, src io.Reader) (*cloudStorage.ObjectAttrs, error) {
+	attrs, err := bucket.Object(objectName).Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pin ke generation yang di-scan, isi yang dipindah harus persis sama
+	obj := bucket.Object(objectName).Generation(attrs.Generation)
+	if src == nil {
+		r, err := obj.NewReader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		src = r
+	}
+
+	verdict, err := scanContent(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	if verdict.Verdict != verdictClean || liveName == "" {
+		updated, err := obj.Update(ctx, cloudStorage.ObjectAttrsToUpdate{Metadata: verdict.metadata()})
+		if err != nil {
+			return nil, err
+		}
+		return updated, verdict.err()
+	}
+
+	copier := bucket.Object(liveName).If(cloudStorage.Conditions{DoesNotExist: true}).CopierFrom(obj)
+	copier.ContentType = attrs.ContentType
+	copier.Metadata = mergeMetadata(attrs.Metadata, verdict.metadata())
+	live, err := copier.Run(ctx)
+	if isNameCollision(err) {
+		return nil, errNameCollision
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bucket.Object(objectName).Delete(context.Background())
+	return live, nil
+}
+
+// scanStoredObject untuk upload baru di karantina. Kalau scan gagal selain
+// karena infected, object karantina dihapus supaya client cukup upload ulang.
+func releaseQuarantined(ctx context.Context, bucket *cloudStorage.BucketHandle, objectName string, liveName string, src io.Reader) (*cloudStorage.ObjectAttrs, error) {
+	attrs, err := scanStoredObject(ctx, bucket, objectName, liveName, src)
+	if err != nil && !errors.Is(err, errInfected) {
+		bucket.Object(objectName).Delete(context.Background())
+	}
+	return attrs, err
+}
+
+// Nama live untuk object yang ditulis ke karantina sebelum di-scan
+func liveObjectName(objectName string) string {
+	return strings.TrimPrefix(objectName, quarantinePrefix)
+}
+
+// Handler POST /objects/*path?action=scan, scan ulang object yang sudah ada (mis.
+// hasil upload langsung lewat signed URL) dan catat verdict-nya
+func scanObjectHandler(c *gin.Context, objectPath string) {
+	if !scanningEnabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "malware scanning is not configured"})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attrs, err := scanStoredObject(c, bucket, objectPath, "", nil)
+	if err != nil && !errors.Is(err, errInfected) {
+		status, _ := uploadErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = storageErrorStatus(err)
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, objectMetaResponse(attrs))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUploadURLTTL = 15 * 60
+	// Batas maksimal expiry untuk signed url V4 (7 hari)
+	maxUploadURLTTL = 7 * 24 * 60 * 60
+)
+
+// Handler untuk generate signed PUT url V4.
+// Content-Type dan ukuran file ikut di-sign, jadi client tidak bisa upload
+// file lain selain yang sudah dideklarasikan.
+func uploadURLHandler(c *gin.Context) {
+	var req types.SignedUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix, err := uploadPrefix(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileType, err := utils.LookupType(req.ContentType)
+	if err != nil || !allowedTypes("UPLOAD_URL").Allows(fileType.MimeType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": errUnsupportedFormat.Error()})
+		return
+	}
+
+	maxSize := envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize)
+	if req.Size <= 0 || req.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be between 1 and max upload size"})
+		return
+	}
+
+	ttl := req.TTLSecond
+	if ttl <= 0 {
+		ttl = defaultUploadURLTTL
+	}
+	if ttl > maxUploadURLTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttl_second exceeds 7 days"})
+		return
+	}
+
+	objectName, err := newObjectName(prefix, uploadTenant(c), fileType.Ext())
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	opts := []URLOption{
+		WithV4(),
+		WithMethod(http.MethodPut),
+		WithContentType(req.ContentType),
+		WithContentLength(req.Size),
+		WithDoesNotExist(),
+	}
+	// Header yang wajib dikirim client persis seperti ini
+	headers := gin.H{
+		"Content-Type":                req.ContentType,
+		"x-goog-content-length-range": formatLengthRange(req.Size),
+		"x-goog-if-generation-match":  "0",
+	}
+	// Verdict ikut di-sign supaya client tidak bisa mengisi scan_verdict sendiri.
+	// Object baru bisa di-download setelah di-scan lewat /objects/*path?action=scan.
+	if scanningEnabled() {
+		opts = append(opts, WithMetadata(scanVerdictKey, verdictPending))
+		headers["x-goog-meta-"+scanVerdictKey] = verdictPending
+	}
+
+	uploadURL, rawURL, err := GenerateURL(objectName, ttl, client, opts...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object_name": objectName,
+		"upload_url":  uploadURL,
+		"raw_url":     rawURL,
+		"method":      http.MethodPut,
+		"headers":     headers,
+		"expires_at":  time.Now().Add(time.Duration(ttl) * time.Second).UTC(),
+	})
+}
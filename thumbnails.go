@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// Subfolder turunan gambar, di samping object aslinya:
+// users/1/abc.png -> users/1/_thumbs/abc.png/128.png
+const thumbnailDir = "_thumbs"
+
+// Prefix key custom metadata object asli yang menunjuk ke thumbnail, mis. thumbnail_128
+const thumbnailMetadataPrefix = "thumbnail_"
+
+const (
+	// Kualitas JPEG untuk thumbnail
+	thumbnailQuality = 85
+	// Default batas piksel gambar yang mau di-decode (bisa di-override via MAX_IMAGE_PIXELS)
+	defaultMaxImagePixels = 40000000
+)
+
+// Prefix semua thumbnail milik objectName
+func thumbnailPrefix(objectName string) string {
+	dir, base := path.Split(objectName)
+	return dir + thumbnailDir + "/" + base + "/"
+}
+
+func thumbnailName(objectName string, size int) string {
+	return thumbnailPrefix(objectName) + strconv.Itoa(size) + path.Ext(objectName)
+}
+
+// Format encoder untuk mime type yang bisa dibuatkan thumbnail
+func thumbnailFormat(contentType string) (string, bool) {
+	switch contentType {
+	case "image/png":
+		return "png", true
+	case "image/jpeg":
+		return "jpeg", true
+	default:
+		return "", false
+	}
+}
+
+// Buat thumbnail best effort untuk object yang baru di-upload. src berisi isi
+// object, nil berarti dibaca ulang dari bucket. Gagal membuat thumbnail tidak
+// menggagalkan upload, cukup di-log. Mengembalikan map ukuran -> nama object.
+func attachThumbnails(ctx context.Context, objectName string, contentType string, src io.Reader, sizes []int) map[string]string {
+	if _, ok := thumbnailFormat(contentType); !ok || len(sizes) == 0 {
+		return nil
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		log.Printf("thumbnail %s: %v", objectName, err)
+		return nil
+	}
+
+	if src == nil {
+		r, err := bucket.Object(objectName).NewReader(ctx)
+		if err != nil {
+			log.Printf("thumbnail %s: %v", objectName, err)
+			return nil
+		}
+		defer r.Close()
+		src = r
+	}
+
+	links, err := generateThumbnails(ctx, bucket, objectName, contentType, src, sizes)
+	if err != nil {
+		log.Printf("thumbnail %s: %v", objectName, err)
+		return nil
+	}
+	return links
+}
+
+// Decode gambar sekali, resize ke setiap ukuran (sisi terpanjang), simpan
+// sebagai object turunan lalu tautkan di custom metadata object asli
+func generateThumbnails(ctx context.Context, bucket *cloudStorage.BucketHandle, objectName string, contentType string, src io.Reader, sizes []int) (map[string]string, error) {
+	format, _ := thumbnailFormat(contentType)
+
+	img, _, err := utils.DecodeImage(src, envInt64("MAX_IMAGE_PIXELS", defaultMaxImagePixels))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+
+	source, err := bucket.Object(objectName).Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Thumbnail berasal dari isi yang sama, hasil scan ikut disalin
+	thumbMetadata := mergeMetadata(scanMetadataOf(source.Metadata), map[string]string{"source_object": objectName})
+
+	links := map[string]string{}
+	metadata := map[string]string{}
+	for _, size := range sizes {
+		// Gambar yang lebih kecil dari size tetap dibuatkan varian (ukuran asli),
+		// supaya URL varian selalu ada
+		w, h := utils.FitSize(bounds.Dx(), bounds.Dy(), size)
+
+		var buf bytes.Buffer
+		if err := utils.EncodeImage(&buf, utils.Resize(img, w, h), format, thumbnailQuality); err != nil {
+			return nil, err
+		}
+
+		name := thumbnailName(objectName, size)
+		if err := writeObject(ctx, bucket.Object(name), contentType, thumbMetadata, buf.Bytes()); err != nil {
+			return nil, err
+		}
+		links[strconv.Itoa(size)] = name
+		metadata[thumbnailMetadataPrefix+strconv.Itoa(size)] = name
+	}
+
+	if _, err := bucket.Object(objectName).Update(ctx, cloudStorage.ObjectAttrsToUpdate{Metadata: metadata}); err != nil {
+		return nil, fmt.Errorf("link thumbnails: %w", err)
+	}
+	return links, nil
+}
+
+// Hapus semua thumbnail milik objectName, best effort
+func deleteThumbnails(ctx context.Context, bucket *cloudStorage.BucketHandle, objectName string) {
+	deleteObjectsWithPrefix(ctx, bucket, thumbnailPrefix(objectName))
+}
+
+// Hapus semua object turunan objectName (thumbnail dan cache /img), dipanggil
+// setelah object aslinya dihapus atau dipindah
+func deleteDerivedObjects(ctx context.Context, bucket *cloudStorage.BucketHandle, objectName string) {
+	deleteThumbnails(ctx, bucket, objectName)
+	deleteObjectsWithPrefix(ctx, bucket, imageCachePrefix(objectName))
+}
+
+func deleteObjectsWithPrefix(ctx context.Context, bucket *cloudStorage.BucketHandle, prefix string) {
+	it := bucket.Objects(ctx, &cloudStorage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return
+		}
+		if err != nil {
+			log.Printf("delete %s: %v", prefix, err)
+			return
+		}
+		bucket.Object(attrs.Name).Delete(ctx)
+	}
+}
+
+// Buat ulang thumbnail object yang isinya diganti di tempat (restore). Key
+// thumbnail_* bawaan metadata lama yang tidak dibuat ulang dikosongkan supaya
+// tidak menunjuk ke varian yang sudah dihapus. Best effort, mengembalikan
+// attrs yang sudah diupdate (nil kalau tidak ada perubahan).
+func regenerateThumbnails(ctx context.Context, bucket *cloudStorage.BucketHandle, attrs *cloudStorage.ObjectAttrs, sizes []int) *cloudStorage.ObjectAttrs {
+	links := attachThumbnails(ctx, attrs.Name, attrs.ContentType, nil, sizes)
+
+	stale := map[string]string{}
+	for key, value := range attrs.Metadata {
+		if strings.HasPrefix(key, thumbnailMetadataPrefix) && value != "" && links[strings.TrimPrefix(key, thumbnailMetadataPrefix)] == "" {
+			stale[key] = ""
+		}
+	}
+	if len(links) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	var (
+		updated *cloudStorage.ObjectAttrs
+		err     error
+	)
+	if len(stale) > 0 {
+		updated, err = bucket.Object(attrs.Name).Update(ctx, cloudStorage.ObjectAttrsToUpdate{Metadata: stale})
+	} else {
+		updated, err = bucket.Object(attrs.Name).Attrs(ctx)
+	}
+	if err != nil {
+		log.Printf("link thumbnails %s: %v", attrs.Name, err)
+		return nil
+	}
+	return updated
+}
+
+// Salin thumbnail src ke lokasi thumbnail dst (copy/move), lalu arahkan
+// key thumbnail_* di metadata dst ke salinannya. Varian yang gagal disalin
+// dikosongkan supaya tidak menunjuk ke object milik src. Best effort seperti
+// attachThumbnails, mengembalikan attrs dst yang sudah diupdate (nil kalau tidak ada).
+func copyThumbnails(ctx context.Context, bucket *cloudStorage.BucketHandle, src string, dst string, metadata map[string]string) *cloudStorage.ObjectAttrs {
+	links := map[string]string{}
+	for key := range metadata {
+		size, err := strconv.Atoi(strings.TrimPrefix(key, thumbnailMetadataPrefix))
+		if !strings.HasPrefix(key, thumbnailMetadataPrefix) || err != nil {
+			continue
+		}
+
+		name := thumbnailName(dst, size)
+		links[key] = ""
+		if _, err := bucket.Object(name).CopierFrom(bucket.Object(thumbnailName(src, size))).Run(ctx); err != nil {
+			log.Printf("copy thumbnail %s: %v", name, err)
+			continue
+		}
+		bucket.Object(name).Update(ctx, cloudStorage.ObjectAttrsToUpdate{Metadata: map[string]string{"source_object": dst}})
+		links[key] = name
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	attrs, err := bucket.Object(dst).Update(ctx, cloudStorage.ObjectAttrsToUpdate{Metadata: links})
+	if err != nil {
+		log.Printf("link thumbnails %s: %v", dst, err)
+		return nil
+	}
+	return attrs
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Implementasi server tus 1.0.0 (https://tus.io/protocols/resumable-upload)
+// dengan extension creation, termination, checksum dan expiration. State
+// upload memakai session yang sama dengan resumable upload, dan chunk digabung
+// lewat compose di bucket, jadi server tidak pernah memegang file utuh.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,checksum,expiration"
+)
+
+// Status code khusus tus untuk checksum yang tidak cocok
+const statusChecksumMismatch = 460
+
+var tusChecksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// Middleware untuk validasi header Tus-Resumable dan set header di setiap response
+func tusResumableMiddleware(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return
+	}
+
+	c.Next()
+}
+
+// Handler OPTIONS, kasih tahu kemampuan server ke client
+func tusOptionsHandler(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize), 10))
+	c.Header("Tus-Checksum-Algorithm", "md5,sha1,sha256")
+	c.Status(http.StatusNoContent)
+}
+
+// Handler POST (creation), membuat upload baru dari header Upload-Length
+func tusCreateHandler(c *gin.Context) {
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Defer-Length is not supported"})
+		return
+	}
+
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Upload-Length"})
+		return
+	}
+	// tus membolehkan Upload-Length 0, tapi file kosong tidak punya format
+	// yang bisa dideteksi, jadi ditolak dengan pesan yang jelas
+	if size == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "empty uploads are not supported"})
+		return
+	}
+	if size > envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errUploadTooLarge.Error()})
+		return
+	}
+
+	metadata := c.GetHeader("Upload-Metadata")
+	if !validTusMetadata(metadata) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Upload-Metadata"})
+		return
+	}
+
+	id, err := createUploadSession(c, firestoreClient, size, uploadTenant(c), metadata)
+	if err != nil {
+		respondSessionError(c, err, nil)
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+id)
+	c.Header("Upload-Expires", time.Now().Add(uploadSessionTTL()).UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// Handler HEAD, client pakai ini untuk tahu offset sebelum resume
+func tusHeadHandler(c *gin.Context) {
+	session, err := getUploadSession(c, firestoreClient, c.Param("id"))
+	if errors.Is(err, errSessionNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errSessionExpired) {
+		c.AbortWithStatus(http.StatusGone)
+		return
+	}
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	if session.Metadata != "" {
+		c.Header("Upload-Metadata", session.Metadata)
+	}
+	if session.Completed {
+		c.Header("X-Object-Name", session.ObjectName)
+	}
+	c.Status(http.StatusOK)
+}
+
+// Handler PATCH (core + checksum), append body di Upload-Offset.
+// Begitu offset mencapai Upload-Length, chunk langsung digabung jadi object final.
+func tusPatchHandler(c *gin.Context) {
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Upload-Offset"})
+		return
+	}
+
+	var checksum *chunkChecksum
+	if header := c.GetHeader("Upload-Checksum"); header != "" {
+		checksum, err = parseUploadChecksum(header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	id := c.Param("id")
+	session, err := appendChunk(c, client, firestoreClient, id, c.Request.Body, offset, c.Request.ContentLength, -1, checksum)
+	if err != nil {
+		switch {
+		case errors.Is(err, errSessionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, errSessionExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		case errors.Is(err, errOffsetMismatch), errors.Is(err, errSessionCompleted):
+			c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, errChecksumMismatch):
+			c.JSON(statusChecksumMismatch, gin.H{"error": err.Error()})
+		case errors.Is(err, errUploadTooLarge), errors.Is(err, errTooManyChunks):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, errBadChunk):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if session.Offset == session.Size {
+		session, err = completeUploadSession(c, client, firestoreClient, id, "TUS")
+		if err != nil {
+			respondSessionError(c, err, nil)
+			return
+		}
+		c.Header("X-Object-Name", session.ObjectName)
+	} else {
+		c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// Handler DELETE (termination), hapus chunk dan session
+func tusDeleteHandler(c *gin.Context) {
+	id := c.Param("id")
+	session, err := getUploadSession(c, firestoreClient, id)
+	if errors.Is(err, errSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	// Session kedaluwarsa tetap boleh dihapus
+	if err != nil && !errors.Is(err, errSessionExpired) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := deleteUploadSession(c, bucket, firestoreClient, id, session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Parse header "Upload-Checksum: <algoritma> <base64 digest>"
+func parseUploadChecksum(header string) (*chunkChecksum, error) {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid Upload-Checksum")
+	}
+
+	newHash, ok := tusChecksumAlgorithms[parts[0]]
+	if !ok {
+		return nil, errors.New("unsupported checksum algorithm: " + parts[0])
+	}
+
+	h := newHash()
+	sum, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil || len(sum) != h.Size() {
+		return nil, errors.New("invalid Upload-Checksum")
+	}
+
+	return &chunkChecksum{hash: h, sum: sum}, nil
+}
+
+// Upload-Metadata berisi pasangan "key base64value" dipisah koma
+func validTusMetadata(metadata string) bool {
+	if metadata == "" {
+		return true
+	}
+
+	for _, pair := range strings.Split(metadata, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return false
+		}
+		if len(fields) == 2 {
+			if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Nilai satu key dari Upload-Metadata (sudah lolos validTusMetadata),
+// kosong kalau tidak ada
+func tusMetadataValue(metadata string, key string) string {
+	for _, pair := range strings.Split(metadata, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 2 && fields[0] == key {
+			value, _ := base64.StdEncoding.DecodeString(fields[1])
+			return string(value)
+		}
+	}
+	return ""
+}
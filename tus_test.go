@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseUploadChecksum(t *testing.T) {
+	body := []byte("hello tus")
+	md5Sum := md5.Sum(body)
+	sha1Sum := sha1.Sum(body)
+	sha256Sum := sha256.Sum256(body)
+	b64 := base64.StdEncoding.EncodeToString
+
+	tests := []struct {
+		name   string
+		header string
+		sum    []byte
+		ok     bool
+	}{
+		{"md5", "md5 " + b64(md5Sum[:]), md5Sum[:], true},
+		{"sha1", "sha1 " + b64(sha1Sum[:]), sha1Sum[:], true},
+		{"sha256", "sha256 " + b64(sha256Sum[:]), sha256Sum[:], true},
+		{"surrounding whitespace", "  sha1 " + b64(sha1Sum[:]) + " ", sha1Sum[:], true},
+		{"empty", "", nil, false},
+		{"missing digest", "sha1", nil, false},
+		{"unsupported algorithm", "crc32 AAAAAA==", nil, false},
+		{"algorithm is case sensitive", "SHA1 " + b64(sha1Sum[:]), nil, false},
+		{"invalid base64", "sha1 not*base64", nil, false},
+		{"url-safe base64", "sha256 " + base64.URLEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 32)), nil, false},
+		{"digest length does not match algorithm", "sha256 " + b64(sha1Sum[:]), nil, false},
+		{"extra field", "sha1 " + b64(sha1Sum[:]) + " extra", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checksum, err := parseUploadChecksum(tt.header)
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("parseUploadChecksum(%q) succeeded, want error", tt.header)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUploadChecksum(%q) error: %v", tt.header, err)
+			}
+			if !bytes.Equal(checksum.sum, tt.sum) {
+				t.Fatalf("sum = %x, want %x", checksum.sum, tt.sum)
+			}
+			checksum.hash.Write(body)
+			if !bytes.Equal(checksum.hash.Sum(nil), tt.sum) {
+				t.Fatal("hash does not match algorithm")
+			}
+		})
+	}
+}
+
+func TestValidTusMetadata(t *testing.T) {
+	tests := []struct {
+		metadata string
+		valid    bool
+	}{
+		{"", true},
+		{"filename d29ybGRfZG9taW5hdGlvbl9wbGFuLnBkZg==", true},
+		{"filename d29ybGQucGRm,filetype YXBwbGljYXRpb24vcGRm", true},
+		{"filename d29ybGQucGRm, filetype YXBwbGljYXRpb24vcGRm", true},
+		{"is_confidential", true},
+		{"filename d29ybGQucGRm,is_confidential", true},
+		{"filename not*base64", false},
+		{"filename d29ybGQucGRm extra", false},
+		{"filename d29ybGQucGRm,,filetype YQ==", false},
+		{",", false},
+		{"filename d29ybGQucGRm,", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.metadata, func(t *testing.T) {
+			if got := validTusMetadata(tt.metadata); got != tt.valid {
+				t.Fatalf("validTusMetadata(%q) = %v, want %v", tt.metadata, got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTusMetadataValue(t *testing.T) {
+	metadata := "filename d29ybGQucGRm,filetype YXBwbGljYXRpb24vcGRm,is_confidential"
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"filename", "world.pdf"},
+		{"filetype", "application/pdf"},
+		{"is_confidential", ""},
+		{"missing", ""},
+		{"file", ""},
+	}
+
+	for _, tt := range tests {
+		if got := tusMetadataValue(metadata, tt.key); got != tt.want {
+			t.Fatalf("tusMetadataValue(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,47 @@
 package types
 
 type UploadRequest struct {
-	FileName   string `json:"file_name"`
-	Base64Data string `json:"base64_data"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Base64Data  string `json:"base64_data"`
 }
 
 type UrlFile struct {
 	Url string `json:"url"`
 }
+
+type SignedUploadRequest struct {
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	TTLSecond   int    `json:"ttl_second"`
+}
+
+type PostPolicyRequest struct {
+	Prefix      string `json:"prefix"`
+	ContentType string `json:"content_type"`
+	MaxSize     int64  `json:"max_size"`
+	TTLSecond   int    `json:"ttl_second"`
+}
+
+type ResumableSessionRequest struct {
+	Size int64 `json:"size"`
+}
+
+type ObjectMetadataRequest struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+type ObjectCopyRequest struct {
+	Destination string `json:"destination"`
+	Overwrite   bool   `json:"overwrite"`
+}
+
+type RestoreVersionRequest struct {
+	Generation int64 `json:"generation"`
+}
+
+type ArchiveRequest struct {
+	Paths  []string `json:"paths"`
+	Prefix string   `json:"prefix"`
+	Name   string   `json:"name"`
+}
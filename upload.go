@@ -0,0 +1,765 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"firebase-poc/types"
+	"firebase-poc/utils"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"firebase.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler untuk upload file base64 ke default bucket.
+// Body JSON tetap di-buffer dan di-decode utuh di memory (dipakai juga untuk
+// strip metadata, dedupe dan scan), jadi ukurannya dibatasi lewat
+// base64BodyLimit. File besar sebaiknya lewat /upload-base64 yang streaming.
+func uploadHandler(c *gin.Context) {
+	var req types.UploadRequest
+	limit := base64BodyLimit(envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize))
+	if !bindLimitedJSON(c, &req, limit, errUploadTooLarge) {
+		return
+	}
+
+	prefix, err := uploadPrefix(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := storeBase64Upload(c, req, prefix, uploadTenant(c), "UPLOAD")
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	if result.Quarantined {
+		respondQuarantined(c, result.ObjectName, result.Mismatch)
+		return
+	}
+
+	signedURL, rawURL, err := GenerateURL(result.ObjectName, 30, client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	body := gin.H{
+		"object_name": result.ObjectName,
+		"signed_url":  signedURL,
+		"raw_url":     rawURL,
+	}
+	if result.Mismatch != nil {
+		body["warnings"] = []*utils.TypeMismatch{result.Mismatch}
+	}
+	if len(result.Thumbnails) > 0 {
+		body["thumbnails"] = result.Thumbnails
+	}
+	addBlobFields(body, result.Blob)
+	c.JSON(http.StatusOK, body)
+}
+
+// Tambahkan info dedupe ke response, id file dipakai untuk melepas referensi
+func addBlobFields(body gin.H, blob *blobResult) {
+	if blob == nil {
+		return
+	}
+	body["file_id"] = blob.FileID
+	body["sha256"] = blob.SHA256
+	body["deduplicated"] = blob.Deduplicated
+}
+
+type base64UploadResult struct {
+	ObjectName  string
+	Quarantined bool
+	Mismatch    *utils.TypeMismatch
+	Blob        *blobResult
+	Thumbnails  map[string]string
+}
+
+// Decode, validasi lalu tulis satu UploadRequest ke bucket. Isi file di-decode
+// utuh ke memory, caller wajib membatasi ukuran body. Allowlist dan
+// policy mismatch diambil dari konfigurasi route yang diberikan.
+func storeBase64Upload(ctx context.Context, req types.UploadRequest, prefix string, tenant string, route string) (*base64UploadResult, error) {
+	data, fileType, declaredType, err := utils.DecodeBase64WithType(req.Base64Data)
+	if errors.Is(err, utils.ErrUnsupportedType) || (err == nil && !allowedTypes(route).Allows(fileType.MimeType)) {
+		return nil, errUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Mime dari data URI dipakai sebagai deklarasi kalau content_type kosong
+	if req.ContentType != "" {
+		declaredType = req.ContentType
+	}
+
+	metadata := originalNameMetadata(req.FileName)
+	quarantined, mismatch, err := resolveTypeMismatch(mismatchPolicy(route), req.FileName, declaredType, fileType)
+	if err != nil {
+		return nil, err
+	}
+	if quarantined {
+		prefix = quarantinePrefix + prefix
+		metadata = quarantineMetadata(metadata, mismatch)
+	}
+
+	if stripMetadataEnabled(route) {
+		var tags map[string]string
+		data, tags, err = sanitizeImageData(data, fileType.MimeType, exifKeepTags(route))
+		if err != nil {
+			return nil, err
+		}
+		metadata = mergeMetadata(metadata, tags)
+	}
+
+	scan := scanningEnabled()
+
+	// File karantina tidak ikut dedupe, harus tetap terpisah sampai direview
+	if !quarantined && dedupeEnabled(route) {
+		// Blob bisa dipakai banyak file, jadi di-scan dari memory sebelum
+		// ditulis. File infected tetap disimpan di karantina untuk direview.
+		var scanMetadata map[string]string
+		if scan {
+			verdict, err := scanContent(ctx, bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			scanMetadata = verdict.metadata()
+			if err := verdict.err(); err != nil {
+				if _, werr := writeNewObject(ctx, client, quarantinePrefix+prefix, tenant, fileType.Ext(), fileType.MimeType, mergeMetadata(metadata, scanMetadata), data); werr != nil {
+					return nil, werr
+				}
+				return nil, err
+			}
+		}
+
+		blob, err := storeBlob(ctx, client, firestoreClient, data, fileType, req.FileName, scanMetadata)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &base64UploadResult{ObjectName: blob.ObjectName, Mismatch: mismatch, Blob: blob}
+		// Blob yang sudah ada sebelumnya sudah punya thumbnail
+		if !blob.Deduplicated {
+			result.Thumbnails = attachThumbnails(ctx, blob.ObjectName, fileType.MimeType, bytes.NewReader(data), thumbnailSizes(route))
+		}
+		return result, nil
+	}
+
+	// Dengan scanning, file ditulis ke karantina dulu dan baru dipindah ke
+	// nama live setelah verdict-nya clean
+	namePrefix := prefix
+	if scan {
+		metadata = mergeMetadata(metadata, map[string]string{scanVerdictKey: verdictPending})
+		if !quarantined {
+			namePrefix = quarantinePrefix + prefix
+		}
+	}
+
+	objectName, err := writeNewObject(ctx, client, namePrefix, tenant, fileType.Ext(), fileType.MimeType, metadata, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if scan {
+		bucket, err := client.DefaultBucket()
+		if err != nil {
+			return nil, err
+		}
+		liveName := ""
+		if !quarantined {
+			liveName = liveObjectName(objectName)
+		}
+		attrs, err := releaseQuarantined(ctx, bucket, objectName, liveName, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		objectName = attrs.Name
+	}
+
+	result := &base64UploadResult{ObjectName: objectName, Quarantined: quarantined, Mismatch: mismatch}
+	if !quarantined {
+		result.Thumbnails = attachThumbnails(ctx, objectName, fileType.MimeType, bytes.NewReader(data), thumbnailSizes(route))
+	}
+	return result, nil
+}
+
+// Prefix tempat file yang belum boleh di-serve disimpan
+const quarantinePrefix = "quarantine/"
+
+var errQuarantined = errors.New("object is quarantined")
+
+// Bandingkan deklarasi client dengan hasil sniffing lalu terapkan policy.
+// Mengembalikan apakah file harus dikarantina, detail mismatch (nil kalau
+// konsisten), atau error *utils.TypeMismatch kalau policy-nya reject.
+func resolveTypeMismatch(policy utils.MismatchPolicy, declaredName string, declaredType string, detected utils.FileType) (bool, *utils.TypeMismatch, error) {
+	mismatch := utils.CheckDeclaredType(declaredName, declaredType, detected)
+	if mismatch == nil {
+		return false, nil, nil
+	}
+
+	switch policy {
+	case utils.PolicyTrustSniffed:
+		return false, mismatch, nil
+	case utils.PolicyQuarantine:
+		return true, mismatch, nil
+	default:
+		return false, mismatch, mismatch
+	}
+}
+
+// Catat alasan karantina di custom metadata object
+func quarantineMetadata(metadata map[string]string, mismatch *utils.TypeMismatch) map[string]string {
+	result := map[string]string{
+		"quarantine_reason":     mismatch.Code,
+		"declared_content_type": mismatch.DeclaredContentType,
+		"detected_content_type": mismatch.DetectedContentType,
+	}
+	for k, v := range metadata {
+		result[k] = v
+	}
+	return result
+}
+
+// File karantina tidak dapat URL, client cukup tahu nama dan alasannya
+func respondQuarantined(c *gin.Context, objectName string, mismatch *utils.TypeMismatch) {
+	c.JSON(http.StatusAccepted, gin.H{
+		"object_name": objectName,
+		"quarantined": true,
+		"mismatch":    mismatch,
+	})
+}
+
+func respondUploadError(c *gin.Context, err error) {
+	status, _ := uploadErrorStatus(err)
+
+	var mismatch *utils.TypeMismatch
+	if errors.As(err, &mismatch) {
+		c.JSON(status, gin.H{"error": mismatch.Message, "details": mismatch})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// Map error upload ke HTTP status dan kode error yang stabil untuk client
+func uploadErrorStatus(err error) (int, string) {
+	var mismatch *utils.TypeMismatch
+	var corrupt base64.CorruptInputError
+	switch {
+	case errors.As(err, &mismatch):
+		return http.StatusUnsupportedMediaType, mismatch.Code
+	case errors.As(err, &corrupt), errors.Is(err, utils.ErrInvalidBase64):
+		return http.StatusBadRequest, "invalid_base64"
+	case errors.Is(err, errUploadTooLarge):
+		return http.StatusRequestEntityTooLarge, "too_large"
+	case errors.Is(err, errUnsupportedFormat):
+		return http.StatusUnsupportedMediaType, "unsupported_type"
+	case errors.Is(err, utils.ErrInvalidObjectPath):
+		return http.StatusBadRequest, "invalid_name"
+	case errors.Is(err, errNameCollision):
+		return http.StatusConflict, "name_collision"
+	case errors.Is(err, utils.ErrInvalidImage):
+		return http.StatusUnprocessableEntity, "invalid_image"
+	case errors.Is(err, utils.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge, "image_too_large"
+	case errors.Is(err, errBlobBusy):
+		return http.StatusServiceUnavailable, "blob_busy"
+	case errors.Is(err, errInfected):
+		return http.StatusUnprocessableEntity, "infected"
+	case errors.Is(err, errScanUnavailable):
+		return http.StatusServiceUnavailable, "scan_unavailable"
+	case errors.Is(err, utils.ErrClamdSizeLimit):
+		return http.StatusRequestEntityTooLarge, "scan_limit"
+	case errors.Is(err, context.Canceled):
+		return http.StatusInternalServerError, "canceled"
+	default:
+		return http.StatusInternalServerError, "storage_error"
+	}
+}
+
+// Handler untuk upload body mentah berupa base64 atau data URI.
+// Body di-decode secara streaming langsung ke bucket, jadi payload besar tidak
+// perlu di-decode utuh di memory seperti di /upload.
+func uploadBase64StreamHandler(c *gin.Context) {
+	prefix, err := uploadPrefix(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decoded, declaredType, err := utils.NewBase64Reader(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if v := c.Query("content_type"); v != "" {
+		declaredType = v
+	}
+
+	fileName := c.Query("file_name")
+	result, err := streamObject(c, client, decoded, streamOptions{
+		Prefix:              prefix,
+		Tenant:              uploadTenant(c),
+		Metadata:            originalNameMetadata(fileName),
+		Allow:               allowedTypes("UPLOAD_BASE64"),
+		Policy:              mismatchPolicy("UPLOAD_BASE64"),
+		Dedupe:              dedupeEnabled("UPLOAD_BASE64"),
+		Thumbnails:          thumbnailSizes("UPLOAD_BASE64"),
+		StripMetadata:       stripMetadataEnabled("UPLOAD_BASE64"),
+		KeepTags:            exifKeepTags("UPLOAD_BASE64"),
+		DeclaredName:        fileName,
+		DeclaredContentType: declaredType,
+		MaxSize:             envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize),
+	})
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	respondStreamResult(c, result)
+}
+
+// Prefix folder opsional dari query ?prefix= (mis. users/123), sudah termasuk
+// slash di akhir supaya bisa langsung digabung dengan nama object
+func uploadPrefix(c *gin.Context) (string, error) {
+	prefix := strings.TrimSuffix(c.Query("prefix"), "/")
+	if prefix == "" {
+		return "", nil
+	}
+
+	prefix, err := utils.NormalizeObjectPath(prefix)
+	if err != nil {
+		return "", err
+	}
+	if isReservedPath(prefix) {
+		return "", errReservedPath
+	}
+	return prefix + "/", nil
+}
+
+var errReservedPath = fmt.Errorf("%w: path is reserved for internal objects", utils.ErrInvalidObjectPath)
+
+// Path yang dipakai server sendiri (blob dedupe, chunk upload, karantina,
+// thumbnail dan cache /img) tidak boleh dipilih client lewat ?prefix=,
+// ?tenant= atau tujuan copy/move
+func isReservedPath(objectPath string) bool {
+	for _, prefix := range []string{blobPrefix, chunkPrefix, quarantinePrefix} {
+		if strings.HasPrefix(objectPath+"/", prefix) {
+			return true
+		}
+	}
+	for _, segment := range strings.Split(objectPath, "/") {
+		if segment == thumbnailDir || segment == imageCacheDir {
+			return true
+		}
+	}
+	return false
+}
+
+// Tenant untuk placeholder {tenant} di OBJECT_NAME_TEMPLATE, dari query ?tenant=
+func uploadTenant(c *gin.Context) string {
+	return c.Query("tenant")
+}
+
+// Custom metadata untuk menyimpan nama file asli dari client (dipakai mis. di archive)
+func originalNameMetadata(filename string) map[string]string {
+	if filename == "" {
+		return nil
+	}
+	return map[string]string{originalNameMetadataKey: path.Base(filename)}
+}
+
+// Jumlah percobaan nama baru kalau nama object ternyata sudah dipakai
+const maxNameAttempts = 3
+
+var errNameCollision = errors.New("object name already exists")
+
+// Nama object baru di bawah prefix sesuai konfigurasi OBJECT_NAME_*
+func newObjectName(prefix string, tenant string, ext string) (string, error) {
+	namer, err := objectNamer()
+	if err != nil {
+		return "", err
+	}
+
+	name, err := namer.Name(utils.NameVars{Tenant: tenant, Ext: ext, Time: time.Now()})
+	if err != nil {
+		return "", err
+	}
+	// Tenant ikut membentuk nama, jadi hasilnya dicek juga. prefix bisa berisi
+	// quarantinePrefix dari server sendiri, yang dicek cukup bagian namer.
+	if isReservedPath(name) {
+		return "", errReservedPath
+	}
+	return prefix + name, nil
+}
+
+// Precondition ifGenerationMatch=0 gagal, berarti object dengan nama itu sudah ada
+func isNameCollision(err error) bool {
+	return storageErrorStatus(err) == http.StatusPreconditionFailed
+}
+
+// Tulis data ke object baru di bawah prefix dengan ifGenerationMatch=0, jadi
+// object yang sudah ada tidak pernah tertimpa. Kalau namanya bentrok dicoba
+// lagi dengan nama baru.
+func writeNewObject(ctx context.Context, client *storage.Client, prefix string, tenant string, ext string, contentType string, metadata map[string]string, data []byte) (string, error) {
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 1; ; attempt++ {
+		objectName, err := newObjectName(prefix, tenant, ext)
+		if err != nil {
+			return "", err
+		}
+
+		err = writeObject(ctx, bucket.Object(objectName).If(cloudStorage.Conditions{DoesNotExist: true}), contentType, metadata, data)
+		if err == nil {
+			return objectName, nil
+		}
+		if !isNameCollision(err) {
+			return "", err
+		}
+		if attempt == maxNameAttempts {
+			return "", errNameCollision
+		}
+	}
+}
+
+// Tulis data ke object dengan Content-Type yang sesuai
+func writeObject(ctx context.Context, obj *cloudStorage.ObjectHandle, contentType string, metadata map[string]string, data []byte) error {
+	w := obj.NewWriter(ctx)
+	w.ContentType = contentType
+	w.Metadata = metadata
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// Key custom metadata untuk nama file asli
+const originalNameMetadataKey = "original_name"
+
+// Default batas ukuran upload multipart (bisa di-override via MAX_UPLOAD_SIZE)
+const defaultMaxUploadSize = 250 << 20
+
+var (
+	errUploadTooLarge    = errors.New("file exceeds maximum upload size")
+	errUnsupportedFormat = errors.New("unsupported file format")
+)
+
+// Batas body JSON untuk file base64 sebesar maxSize: base64 sekitar 4/3
+// ukuran file, ditambah ruang untuk newline di dalam string base64 dan field
+// JSON lain
+func base64BodyLimit(maxSize int64) int64 {
+	encoded := (maxSize + 2) / 3 * 4
+	return encoded + encoded/32 + 64<<10
+}
+
+// Bind body JSON dengan batas ukuran, supaya body yang terlalu besar dibalas
+// 413 sebelum sempat di-buffer utuh di memory
+func bindLimitedJSON(c *gin.Context, obj interface{}, limit int64, tooLarge error) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	if err := c.ShouldBindJSON(obj); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge.Error()})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// Handler untuk upload multipart/form-data secara streaming.
+// Body request langsung di-pipe ke storage.Writer, jadi file tidak pernah
+// di-buffer utuh di memory.
+func uploadMultipartHandler(c *gin.Context) {
+	maxSize := envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize)
+
+	prefix, err := uploadPrefix(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Cari part "file", part lain di-skip
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if err == io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing file field"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if part.FormName() == "file" {
+			break
+		}
+		part.Close()
+	}
+	defer part.Close()
+
+	result, err := streamObject(c, client, part, streamOptions{
+		Prefix:              prefix,
+		Tenant:              uploadTenant(c),
+		Metadata:            originalNameMetadata(part.FileName()),
+		Allow:               allowedTypes("UPLOAD_MULTIPART"),
+		Policy:              mismatchPolicy("UPLOAD_MULTIPART"),
+		Dedupe:              dedupeEnabled("UPLOAD_MULTIPART"),
+		Thumbnails:          thumbnailSizes("UPLOAD_MULTIPART"),
+		StripMetadata:       stripMetadataEnabled("UPLOAD_MULTIPART"),
+		KeepTags:            exifKeepTags("UPLOAD_MULTIPART"),
+		DeclaredName:        part.FileName(),
+		DeclaredContentType: part.Header.Get("Content-Type"),
+		MaxSize:             maxSize,
+	})
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	respondStreamResult(c, result)
+}
+
+func respondStreamResult(c *gin.Context, result *streamResult) {
+	if result.Quarantined {
+		respondQuarantined(c, result.ObjectName, result.Mismatch)
+		return
+	}
+
+	signedURL, rawURL, err := GenerateURL(result.ObjectName, 30, client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	body := gin.H{
+		"object_name":  result.ObjectName,
+		"content_type": result.ContentType,
+		"size":         result.Size,
+		"md5":          result.MD5,
+		"crc32c":       result.CRC32C,
+		"signed_url":   signedURL,
+		"raw_url":      rawURL,
+	}
+	if result.Mismatch != nil {
+		body["warnings"] = []*utils.TypeMismatch{result.Mismatch}
+	}
+	if len(result.Thumbnails) > 0 {
+		body["thumbnails"] = result.Thumbnails
+	}
+	addBlobFields(body, result.Blob)
+	c.JSON(http.StatusOK, body)
+}
+
+type streamOptions struct {
+	Prefix   string
+	Tenant   string
+	Metadata map[string]string
+	Allow    utils.Allowlist
+	MaxSize  int64
+
+	// Deklarasi dari client untuk dicek terhadap hasil sniffing
+	Policy              utils.MismatchPolicy
+	DeclaredName        string
+	DeclaredContentType string
+
+	// Simpan sebagai blob content-addressed (nama dari SHA-256 isi)
+	Dedupe bool
+	// Ukuran thumbnail untuk PNG/JPEG, kosong berarti tanpa thumbnail
+	Thumbnails []int
+	// Buang metadata PNG/JPEG sebelum ditulis, tag di KeepTags disalin ke custom metadata
+	StripMetadata bool
+	KeepTags      []string
+}
+
+type streamResult struct {
+	ObjectName  string
+	ContentType string
+	Size        int64
+	MD5         string
+	CRC32C      string
+	Quarantined bool
+	Mismatch    *utils.TypeMismatch
+	Blob        *blobResult
+	Thumbnails  map[string]string
+}
+
+// Stream isi src ke object baru di default bucket, di bawah opts.Prefix.
+// Mime type dideteksi dari byte awal, harus ada di opts.Allow dan dicek
+// terhadap deklarasi client. Ukuran dibatasi opts.MaxSize, dan MD5/CRC32C
+// dihitung sambil menulis lalu dicocokkan dengan hasil dari GCS.
+func streamObject(ctx context.Context, client *storage.Client, src io.Reader, opts streamOptions) (*streamResult, error) {
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(src, utils.SniffLen)
+	head, err := br.Peek(utils.SniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	fileType, err := utils.DetectType(head)
+	if err != nil || !opts.Allow.Allows(fileType.MimeType) {
+		return nil, errUnsupportedFormat
+	}
+
+	prefix, metadata := opts.Prefix, opts.Metadata
+	quarantined, mismatch, err := resolveTypeMismatch(opts.Policy, opts.DeclaredName, opts.DeclaredContentType, fileType)
+	if err != nil {
+		return nil, err
+	}
+	if quarantined {
+		prefix = quarantinePrefix + prefix
+		metadata = quarantineMetadata(metadata, mismatch)
+	}
+
+	var body io.Reader = br
+	if opts.StripMetadata {
+		var tags map[string]string
+		body, tags, err = sanitizeImage(br, fileType.MimeType, opts.KeepTags, opts.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		metadata = mergeMetadata(metadata, tags)
+	}
+
+	// Cancel context untuk abort upload, object tidak akan di-commit
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stream tidak bisa diulang, jadi nama yang bentrok langsung jadi error.
+	// Mode dedupe menulis ke object sementara dulu karena hash baru diketahui
+	// setelah stream selesai. Dengan scanning, object ditulis ke karantina dan
+	// baru dipindah setelah verdict-nya clean.
+	scan := scanningEnabled()
+	dedupe := opts.Dedupe && !quarantined
+	tempPrefix := chunkPrefix
+	if scan {
+		metadata = mergeMetadata(metadata, map[string]string{scanVerdictKey: verdictPending})
+		tempPrefix = quarantinePrefix
+		if !quarantined {
+			prefix = quarantinePrefix + prefix
+		}
+	}
+
+	var objectName string
+	if dedupe {
+		objectName, err = utils.RandomString(32)
+		objectName = tempPrefix + "dedupe/" + objectName
+	} else {
+		objectName, err = newObjectName(prefix, opts.Tenant, fileType.Ext())
+	}
+	if err != nil {
+		return nil, err
+	}
+	w := bucket.Object(objectName).If(cloudStorage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	w.ContentType = fileType.MimeType
+	w.Metadata = metadata
+
+	md5Hash := md5.New()
+	crcHash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	shaHash := sha256.New()
+
+	// Baca satu byte lebih dari batas supaya file yang kebesaran ketahuan
+	n, err := io.Copy(io.MultiWriter(w, md5Hash, crcHash, shaHash), io.LimitReader(body, opts.MaxSize+1))
+	if err != nil {
+		cancel()
+		w.Close()
+		if isNameCollision(err) {
+			return nil, errNameCollision
+		}
+		return nil, err
+	}
+	if n > opts.MaxSize {
+		cancel()
+		w.Close()
+		return nil, errUploadTooLarge
+	}
+
+	if err := w.Close(); err != nil {
+		if isNameCollision(err) {
+			return nil, errNameCollision
+		}
+		return nil, err
+	}
+
+	attrs := w.Attrs()
+	sum := md5Hash.Sum(nil)
+	if !bytes.Equal(attrs.MD5, sum) || attrs.CRC32C != crcHash.Sum32() {
+		// Object corrupt di jalan, hapus saja
+		bucket.Object(objectName).Delete(context.Background())
+		return nil, errors.New("checksum mismatch after upload")
+	}
+
+	result := &streamResult{
+		ObjectName:  objectName,
+		ContentType: fileType.MimeType,
+		Size:        n,
+		MD5:         encodeMD5(sum),
+		CRC32C:      encodeCRC32C(crcHash.Sum32()),
+		Quarantined: quarantined,
+		Mismatch:    mismatch,
+	}
+
+	var scanMetadata map[string]string
+	if scan {
+		liveName := ""
+		if !quarantined && !dedupe {
+			liveName = liveObjectName(objectName)
+		}
+		attrs, err := releaseQuarantined(ctx, bucket, objectName, liveName, nil)
+		if err != nil {
+			return nil, err
+		}
+		result.ObjectName = attrs.Name
+		scanMetadata = scanMetadataOf(attrs.Metadata)
+	}
+
+	if dedupe {
+		blob, err := promoteToBlob(ctx, client, firestoreClient, objectName, hex.EncodeToString(shaHash.Sum(nil)), fileType.MimeType, fileType.Ext(), n, opts.Metadata[originalNameMetadataKey], scanMetadata)
+		if err != nil {
+			return nil, err
+		}
+		result.ObjectName = blob.ObjectName
+		result.Blob = blob
+	}
+
+	// Isi stream sudah habis, thumbnail dibuat dari object yang sudah tersimpan
+	if !quarantined && (result.Blob == nil || !result.Blob.Deduplicated) {
+		result.Thumbnails = attachThumbnails(ctx, result.ObjectName, fileType.MimeType, nil, opts.Thumbnails)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"firebase-poc/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBase64BodyLimit(t *testing.T) {
+	for _, size := range []int64{0, 1, 3, 1 << 20, defaultMaxUploadSize} {
+		encoded := (size + 2) / 3 * 4
+		if limit := base64BodyLimit(size); limit < encoded+encoded/38 {
+			t.Errorf("base64BodyLimit(%d) = %d, too small for %d base64 bytes", size, limit, encoded)
+		}
+	}
+}
+
+func TestBindLimitedJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		body   string
+		ok     bool
+		status int
+	}{
+		{"within limit", `{"file_name":"a.txt","base64_data":"aGVsbG8="}`, true, http.StatusOK},
+		{"too large", `{"base64_data":"` + strings.Repeat("A", 200) + `"}`, false, http.StatusRequestEntityTooLarge},
+		{"invalid json", `{"base64_data":`, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var req types.UploadRequest
+			if ok := bindLimitedJSON(c, &req, 100, errUploadTooLarge); ok != tt.ok {
+				t.Fatalf("bindLimitedJSON = %v, want %v", ok, tt.ok)
+			}
+			if !tt.ok && w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.ok && req.Base64Data != "aGVsbG8=" {
+				t.Fatalf("Base64Data = %q", req.Base64Data)
+			}
+		})
+	}
+}
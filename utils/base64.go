@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrInvalidBase64 = errors.New("invalid base64 data")
+
+// Batas panjang header data URI sebelum koma, mis. "data:image/png;base64,"
+const maxDataURIHeader = 256
+
+// NewBase64Reader membungkus r dan mengembalikan reader hasil decode.
+// Input boleh berupa data URI (data:<mime>;base64,...), alfabet standar
+// maupun URL-safe, dengan atau tanpa padding, dan boleh mengandung
+// whitespace/newline. Mime type dari data URI dikembalikan sebagai hint
+// (kosong kalau tidak ada). Decode berjalan streaming, tidak pernah
+// menampung seluruh payload di memory.
+func NewBase64Reader(r io.Reader) (io.Reader, string, error) {
+	br := bufio.NewReaderSize(r, maxDataURIHeader)
+
+	if err := skipWhitespace(br); err != nil && err != io.EOF {
+		return nil, "", err
+	}
+
+	declaredType := ""
+	prefix, _ := br.Peek(len("data:"))
+	if strings.EqualFold(string(prefix), "data:") {
+		header, err := br.ReadSlice(',')
+		if err != nil {
+			return nil, "", ErrInvalidBase64
+		}
+
+		declaredType, err = parseDataURIHeader(string(header[len("data:") : len(header)-1]))
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return base64.NewDecoder(base64.RawStdEncoding, &base64Filter{r: br}), declaredType, nil
+}
+
+// Header data URI: [<mediatype>][;param=value]*;base64
+func parseDataURIHeader(header string) (string, error) {
+	parts := strings.Split(header, ";")
+	if len(parts) < 2 || !strings.EqualFold(parts[len(parts)-1], "base64") {
+		return "", fmt.Errorf("%w: data URI must be base64 encoded", ErrInvalidBase64)
+	}
+	return strings.ToLower(strings.TrimSpace(parts[0])), nil
+}
+
+func skipWhitespace(br *bufio.Reader) error {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if !isBase64Space(b) {
+			return br.UnreadByte()
+		}
+	}
+}
+
+func isBase64Space(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// base64Filter membuang whitespace dan padding, dan mengubah alfabet URL-safe
+// ke alfabet standar, supaya hasilnya bisa di-decode RawStdEncoding
+type base64Filter struct {
+	r      io.Reader
+	padded bool
+}
+
+func (f *base64Filter) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+
+		j := 0
+		for _, b := range p[:n] {
+			switch {
+			case isBase64Space(b):
+				continue
+			case b == '=':
+				f.padded = true
+				continue
+			case f.padded:
+				// Padding hanya boleh ada di akhir
+				return j, ErrInvalidBase64
+			case b == '-':
+				b = '+'
+			case b == '_':
+				b = '/'
+			}
+			p[j] = b
+			j++
+		}
+
+		if j > 0 || err != nil || n == 0 {
+			return j, err
+		}
+	}
+}
+
+// DecodeBase64 decode seluruh string sekaligus dengan aturan yang sama
+// seperti NewBase64Reader
+func DecodeBase64(data string) ([]byte, string, error) {
+	r, declaredType, err := NewBase64Reader(strings.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(base64.RawStdEncoding.DecodedLen(len(data)))
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), declaredType, nil
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeBase64(t *testing.T) {
+	// 0xfb 0xff 0xfe butuh karakter +/ (atau -_ di alfabet URL-safe)
+	binary := []byte{0xfb, 0xff, 0xfe, 'h', 'i'}
+
+	tests := []struct {
+		name         string
+		in           string
+		want         []byte
+		declaredType string
+		err          bool
+	}{
+		{"standard padded", "aGVsbG8=", []byte("hello"), "", false},
+		{"standard raw", "aGVsbG8", []byte("hello"), "", false},
+		{"standard alphabet", "+//+aGk=", binary, "", false},
+		{"url-safe alphabet", "-__-aGk", binary, "", false},
+		{"data uri", "data:image/png;base64,aGVsbG8=", []byte("hello"), "image/png", false},
+		{"data uri params", "DATA:Image/PNG;name=a.png;base64,aGVsbG8=", []byte("hello"), "image/png", false},
+		{"data uri without type", "data:;base64,aGVsbG8=", []byte("hello"), "", false},
+		{"leading whitespace", "\n\t  data:text/plain;base64,aGVsbG8=", []byte("hello"), "text/plain", false},
+		{"line wrapped", "aGVs\r\nbG8g\nd29y bGQ=\n", []byte("hello world"), "", false},
+		{"empty", "", []byte{}, "", false},
+		{"data uri not base64", "data:text/plain,hello", nil, "", true},
+		{"data uri without comma", "data:text/plain;base64", nil, "", true},
+		{"padding in the middle", "aGVs=bG8=", nil, "", true},
+		{"padding then whitespace then data", "aGVsbA== \nbG8=", nil, "", true},
+		{"invalid character", "aGVs!G8=", nil, "", true},
+		{"truncated quantum", "aGVsb", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, declaredType, err := DecodeBase64(tt.in)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("DecodeBase64(%q) = %q, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeBase64(%q) error: %v", tt.in, err)
+			}
+			if !bytes.Equal(got, tt.want) || declaredType != tt.declaredType {
+				t.Fatalf("DecodeBase64(%q) = %q, %q, want %q, %q", tt.in, got, declaredType, tt.want, tt.declaredType)
+			}
+		})
+	}
+}
+
+func TestNewBase64ReaderSmallReads(t *testing.T) {
+	want := bytes.Repeat([]byte("firebase-poc "), 100)
+	in := "data:text/plain;base64," + wrapLines(base64.StdEncoding.EncodeToString(want), 76)
+
+	r, declaredType, err := NewBase64Reader(iotest.OneByteReader(strings.NewReader(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) || declaredType != "text/plain" {
+		t.Fatalf("got %d bytes, type %q", len(got), declaredType)
+	}
+}
+
+func TestNewBase64ReaderMidPadding(t *testing.T) {
+	r, _, err := NewBase64Reader(strings.NewReader("aGVs=bG8="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrInvalidBase64) {
+		t.Fatalf("err = %v, want ErrInvalidBase64", err)
+	}
+}
+
+func wrapLines(s string, width int) string {
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width] + "\n")
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
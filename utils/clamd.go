@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	ErrClamdAddress   = errors.New("invalid clamd address")
+	ErrClamdSizeLimit = errors.New("file exceeds scanner stream limit")
+	ErrClamdResponse  = errors.New("unexpected clamd response")
+)
+
+// Ukuran chunk INSTREAM, jauh di bawah StreamMaxLength default clamd
+const clamdChunkSize = 64 << 10
+
+// Clamd adalah client daemon clamd (atau yang kompatibel) lewat TCP atau Unix socket
+type Clamd struct {
+	Network string
+	Address string
+	// Batas waktu satu scan dari connect sampai verdict, 0 berarti tanpa batas
+	Timeout time.Duration
+}
+
+type ScanResult struct {
+	Infected bool
+	// Nama signature yang cocok, mis. Eicar-Test-Signature
+	Signature string
+}
+
+// ParseClamdAddress menerima tcp://host:port, unix:///path/clamd.sock, atau
+// bentuk pendek host:port dan /path/clamd.sock
+func ParseClamdAddress(s string) (*Clamd, error) {
+	switch {
+	case strings.HasPrefix(s, "unix://"):
+		s = strings.TrimPrefix(s, "unix://")
+		if s == "" {
+			return nil, ErrClamdAddress
+		}
+		return &Clamd{Network: "unix", Address: s}, nil
+	case strings.HasPrefix(s, "/"):
+		return &Clamd{Network: "unix", Address: s}, nil
+	}
+
+	s = strings.TrimPrefix(s, "tcp://")
+	if _, port, err := net.SplitHostPort(s); err != nil || port == "" {
+		return nil, fmt.Errorf("%w: %s", ErrClamdAddress, s)
+	}
+	return &Clamd{Network: "tcp", Address: s}, nil
+}
+
+// Scan mengirim isi r ke clamd dengan perintah INSTREAM dan mengembalikan verdict-nya
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Putus koneksi kalau ctx dibatalkan di tengah scan
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	result, err := clamdInstream(conn, r)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return result, err
+}
+
+func clamdInstream(conn net.Conn, r io.Reader) (*ScanResult, error) {
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				// clamd menutup koneksi setelah membalas kalau limit terlewati
+				if result, rerr := readClamdReply(conn); rerr == nil || errors.Is(rerr, ErrClamdSizeLimit) {
+					return result, rerr
+				}
+				return nil, werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Chunk dengan panjang 0 menandai akhir stream
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+	return readClamdReply(conn)
+}
+
+// Balasan perintah berawalan z diakhiri NUL, mis. "stream: OK" atau
+// "stream: Eicar-Test-Signature FOUND"
+func readClamdReply(conn net.Conn) (*ScanResult, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return nil, err
+	}
+
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.Contains(reply, "size limit exceeded"):
+		return nil, ErrClamdSizeLimit
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrClamdResponse, reply)
+	}
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadClamdReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  *ScanResult
+		err   error
+	}{
+		{"ok", "stream: OK\x00", &ScanResult{}, nil},
+		{"ok without stream prefix", "OK\x00", &ScanResult{}, nil},
+		{"ok without nul", "stream: OK", &ScanResult{}, nil},
+		{"found", "stream: Eicar-Test-Signature FOUND\x00", &ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil},
+		{"found with newline", "stream: Win.Test.EICAR_HDB-1 FOUND\n\x00", &ScanResult{Infected: true, Signature: "Win.Test.EICAR_HDB-1"}, nil},
+		{"size limit", "INSTREAM size limit exceeded. ERROR\x00", nil, ErrClamdSizeLimit},
+		{"error", "stream: Can't allocate memory ERROR\x00", nil, ErrClamdResponse},
+		{"garbage", "\x01\x02garbage\x00", nil, ErrClamdResponse},
+		{"empty reply", "\x00", nil, ErrClamdResponse},
+		{"closed without reply", "", nil, io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			go func() {
+				io.WriteString(server, tt.reply)
+				server.Close()
+			}()
+			defer client.Close()
+
+			got, err := readClamdReply(client)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("readClamdReply(%q) = %+v, %v, want %v", tt.reply, got, err, tt.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("readClamdReply(%q) = %+v, %v, want %+v", tt.reply, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClamdAddress(t *testing.T) {
+	tests := []struct {
+		in      string
+		network string
+		address string
+		err     bool
+	}{
+		{"tcp://clamd:3310", "tcp", "clamd:3310", false},
+		{"clamd:3310", "tcp", "clamd:3310", false},
+		{"[::1]:3310", "tcp", "[::1]:3310", false},
+		{"unix:///run/clamav/clamd.sock", "unix", "/run/clamav/clamd.sock", false},
+		{"/run/clamav/clamd.sock", "unix", "/run/clamav/clamd.sock", false},
+		{"", "", "", true},
+		{"clamd", "", "", true},
+		{"tcp://clamd", "", "", true},
+		{"clamd:", "", "", true},
+		{"unix://", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			c, err := ParseClamdAddress(tt.in)
+			if tt.err {
+				if !errors.Is(err, ErrClamdAddress) {
+					t.Fatalf("ParseClamdAddress(%q) = %+v, %v, want ErrClamdAddress", tt.in, c, err)
+				}
+				return
+			}
+			if err != nil || c.Network != tt.network || c.Address != tt.address {
+				t.Fatalf("ParseClamdAddress(%q) = %+v, %v, want %s %s", tt.in, c, err, tt.network, tt.address)
+			}
+		})
+	}
+}
+
+// clamd palsu: baca INSTREAM lalu balas dengan reply(isi stream)
+func fakeClamd(t *testing.T, reply func(data []byte) string) *Clamd {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var data []byte
+				for {
+					var size [4]byte
+					if _, err := io.ReadFull(r, size[:]); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size[:])
+					if n == 0 {
+						break
+					}
+					chunk := make([]byte, n)
+					if _, err := io.ReadFull(r, chunk); err != nil {
+						return
+					}
+					data = append(data, chunk...)
+				}
+				io.WriteString(conn, reply(data))
+			}()
+		}
+	}()
+
+	return &Clamd{Network: "tcp", Address: ln.Addr().String(), Timeout: 5 * time.Second}
+}
+
+func TestClamdScan(t *testing.T) {
+	clamd := fakeClamd(t, func(data []byte) string {
+		switch {
+		case bytes.Contains(data, []byte("EICAR")):
+			return "stream: Eicar-Test-Signature FOUND\x00"
+		case len(data) > 3*clamdChunkSize:
+			return "INSTREAM size limit exceeded. ERROR\x00"
+		default:
+			return "stream: OK\x00"
+		}
+	})
+
+	tests := []struct {
+		name string
+		data string
+		want *ScanResult
+		err  error
+	}{
+		{"clean", "hello", &ScanResult{}, nil},
+		{"empty", "", &ScanResult{}, nil},
+		{"multiple chunks", strings.Repeat("a", 2*clamdChunkSize+10), &ScanResult{}, nil},
+		{"infected", "xxEICARxx", &ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil},
+		{"size limit", strings.Repeat("a", 3*clamdChunkSize+1), nil, ErrClamdSizeLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clamd.Scan(context.Background(), strings.NewReader(tt.data))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Scan = %+v, %v, want %v", got, err, tt.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Scan = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamdScanCanceled(t *testing.T) {
+	clamd := fakeClamd(t, func([]byte) string { return "stream: OK\x00" })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := clamd.Scan(ctx, strings.NewReader("hello")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
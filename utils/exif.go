@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IFD tempat sebuah tag EXIF berada
+type exifIFD int
+
+const (
+	ifd0 exifIFD = iota
+	exifSubIFD
+	gpsIFD
+)
+
+type exifTagKey struct {
+	ifd exifIFD
+	tag uint16
+}
+
+// Tag EXIF yang dikenali dan boleh disalin ke custom metadata lewat whitelist
+var exifTagNames = map[exifTagKey]string{
+	{ifd0, 0x010f}:       "Make",
+	{ifd0, 0x0110}:       "Model",
+	{ifd0, 0x0112}:       "Orientation",
+	{ifd0, 0x0131}:       "Software",
+	{ifd0, 0x0132}:       "DateTime",
+	{ifd0, 0x013b}:       "Artist",
+	{ifd0, 0x8298}:       "Copyright",
+	{exifSubIFD, 0x829a}: "ExposureTime",
+	{exifSubIFD, 0x829d}: "FNumber",
+	{exifSubIFD, 0x8827}: "ISOSpeedRatings",
+	{exifSubIFD, 0x9003}: "DateTimeOriginal",
+	{exifSubIFD, 0x9004}: "DateTimeDigitized",
+	{exifSubIFD, 0x920a}: "FocalLength",
+	{exifSubIFD, 0xa002}: "PixelXDimension",
+	{exifSubIFD, 0xa003}: "PixelYDimension",
+	{exifSubIFD, 0xa434}: "LensModel",
+	{gpsIFD, 0x0001}:     "GPSLatitudeRef",
+	{gpsIFD, 0x0002}:     "GPSLatitude",
+	{gpsIFD, 0x0003}:     "GPSLongitudeRef",
+	{gpsIFD, 0x0004}:     "GPSLongitude",
+	{gpsIFD, 0x0005}:     "GPSAltitudeRef",
+	{gpsIFD, 0x0006}:     "GPSAltitude",
+}
+
+// Pointer ke sub-IFD di dalam IFD0
+const (
+	exifPointerTag = 0x8769
+	gpsPointerTag  = 0x8825
+)
+
+// Ukuran satu nilai per tipe TIFF: BYTE, ASCII, SHORT, LONG, RATIONAL,
+// SBYTE, UNDEFINED, SSHORT, SLONG, SRATIONAL
+var tiffTypeSize = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8}
+
+// Batas jumlah entry per IFD, data rusak tidak boleh bikin loop panjang
+const maxIFDEntries = 1024
+
+// parseExif membaca data TIFF di dalam segmen EXIF dan mengembalikan tag yang
+// dikenali (nama -> nilai dalam bentuk teks). Data yang rusak di tengah jalan
+// tidak dianggap error, tag yang sudah terbaca tetap dikembalikan.
+func parseExif(data []byte) (map[string]string, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: short exif header", ErrInvalidImage)
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: bad exif byte order", ErrInvalidImage)
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return nil, fmt.Errorf("%w: bad exif magic", ErrInvalidImage)
+	}
+
+	p := &exifParser{data: data, order: order, tags: map[string]string{}}
+	pointers := p.readIFD(ifd0, order.Uint32(data[4:]))
+	if offset, ok := pointers[exifPointerTag]; ok {
+		p.readIFD(exifSubIFD, offset)
+	}
+	if offset, ok := pointers[gpsPointerTag]; ok {
+		p.readIFD(gpsIFD, offset)
+	}
+	return p.tags, nil
+}
+
+type exifParser struct {
+	data  []byte
+	order binary.ByteOrder
+	tags  map[string]string
+}
+
+// Baca satu IFD, mengembalikan nilai tag pointer sub-IFD yang ditemukan
+func (p *exifParser) readIFD(ifd exifIFD, offset uint32) map[uint16]uint32 {
+	pointers := map[uint16]uint32{}
+	if uint64(offset)+2 > uint64(len(p.data)) {
+		return pointers
+	}
+
+	count := int(p.order.Uint16(p.data[offset:]))
+	if count > maxIFDEntries {
+		return pointers
+	}
+
+	for i := 0; i < count; i++ {
+		entry := uint64(offset) + 2 + uint64(i)*12
+		if entry+12 > uint64(len(p.data)) {
+			return pointers
+		}
+		e := p.data[entry : entry+12]
+		tag := p.order.Uint16(e[0:])
+		typ := p.order.Uint16(e[2:])
+		n := p.order.Uint32(e[4:])
+
+		if ifd == ifd0 && (tag == exifPointerTag || tag == gpsPointerTag) && typ == 4 {
+			pointers[tag] = p.order.Uint32(e[8:])
+			continue
+		}
+
+		name, ok := exifTagNames[exifTagKey{ifd, tag}]
+		if !ok {
+			continue
+		}
+		if value, ok := p.value(typ, n, e[8:12]); ok {
+			p.tags[name] = value
+		}
+	}
+	return pointers
+}
+
+// Format nilai tag jadi teks. Nilai > 4 byte disimpan di offset.
+func (p *exifParser) value(typ uint16, count uint32, inline []byte) (string, bool) {
+	size, ok := tiffTypeSize[typ]
+	if !ok || count == 0 || count > 1<<16 {
+		return "", false
+	}
+
+	total := uint64(size) * uint64(count)
+	var raw []byte
+	if total > 4 {
+		offset := uint64(p.order.Uint32(inline))
+		if offset+total > uint64(len(p.data)) {
+			return "", false
+		}
+		raw = p.data[offset : offset+total]
+	} else {
+		raw = inline[:total]
+	}
+
+	if typ == 2 {
+		return strings.TrimSpace(strings.TrimRight(string(raw), "\x00")), true
+	}
+
+	values := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		v := raw[uint64(i)*uint64(size):]
+		switch typ {
+		case 1:
+			values = append(values, strconv.Itoa(int(v[0])))
+		case 3:
+			values = append(values, strconv.Itoa(int(p.order.Uint16(v))))
+		case 4:
+			values = append(values, strconv.FormatUint(uint64(p.order.Uint32(v)), 10))
+		case 8:
+			values = append(values, strconv.Itoa(int(int16(p.order.Uint16(v)))))
+		case 9:
+			values = append(values, strconv.Itoa(int(int32(p.order.Uint32(v)))))
+		case 5:
+			values = append(values, fmt.Sprintf("%d/%d", p.order.Uint32(v), p.order.Uint32(v[4:])))
+		case 10:
+			values = append(values, fmt.Sprintf("%d/%d", int32(p.order.Uint32(v)), int32(p.order.Uint32(v[4:]))))
+		default:
+			return "", false
+		}
+	}
+	return strings.Join(values, ","), true
+}
@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag: tag, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func shortEntry(order binary.ByteOrder, tag uint16, v uint16) tiffEntry {
+	value := make([]byte, 2)
+	order.PutUint16(value, v)
+	return tiffEntry{tag: tag, typ: 3, count: 1, value: value}
+}
+
+func rationalEntry(order binary.ByteOrder, tag uint16, num uint32, den uint32) tiffEntry {
+	value := make([]byte, 8)
+	order.PutUint32(value, num)
+	order.PutUint32(value[4:], den)
+	return tiffEntry{tag: tag, typ: 5, count: 1, value: value}
+}
+
+// Bangun data TIFF: header, IFD0, Exif sub-IFD (kalau ada), lalu area nilai
+// yang lebih dari 4 byte
+func buildTIFF(order binary.ByteOrder, ifd0 []tiffEntry, sub []tiffEntry) []byte {
+	ifdSize := func(n int) uint32 { return uint32(2 + 12*n + 4) }
+	n0 := len(ifd0)
+	if sub != nil {
+		n0++
+	}
+	subOffset := 8 + ifdSize(n0)
+	dataOffset := subOffset
+	if sub != nil {
+		dataOffset += ifdSize(len(sub))
+	}
+
+	data := make([]byte, dataOffset)
+	if order == binary.LittleEndian {
+		copy(data, "II")
+	} else {
+		copy(data, "MM")
+	}
+	order.PutUint16(data[2:], 42)
+	order.PutUint32(data[4:], 8)
+
+	writeIFD := func(offset uint32, entries []tiffEntry) {
+		order.PutUint16(data[offset:], uint16(len(entries)))
+		for i, e := range entries {
+			p := data[offset+2+uint32(i)*12:]
+			order.PutUint16(p, e.tag)
+			order.PutUint16(p[2:], e.typ)
+			order.PutUint32(p[4:], e.count)
+			if len(e.value) <= 4 {
+				copy(p[8:12], e.value)
+				continue
+			}
+			order.PutUint32(p[8:], uint32(len(data)))
+			data = append(data, e.value...)
+		}
+	}
+
+	entries := ifd0
+	if sub != nil {
+		pointer := make([]byte, 4)
+		order.PutUint32(pointer, subOffset)
+		entries = append(append([]tiffEntry{}, ifd0...), tiffEntry{tag: exifPointerTag, typ: 4, count: 1, value: pointer})
+	}
+	writeIFD(8, entries)
+	if sub != nil {
+		writeIFD(subOffset, sub)
+	}
+	return data
+}
+
+func sampleTIFF(order binary.ByteOrder) []byte {
+	return buildTIFF(order,
+		[]tiffEntry{
+			asciiEntry(0x010f, "Canon"),
+			shortEntry(order, 0x0112, 6),
+			asciiEntry(0x013b, "Ab"),
+			{tag: 0x9999, typ: 3, count: 1, value: []byte{1, 0}},
+		},
+		[]tiffEntry{
+			asciiEntry(0x9003, "2023:01:02 03:04:05"),
+			rationalEntry(order, 0x829d, 28, 10),
+		},
+	)
+}
+
+func TestParseExif(t *testing.T) {
+	want := map[string]string{
+		"Make":             "Canon",
+		"Orientation":      "6",
+		"Artist":           "Ab",
+		"DateTimeOriginal": "2023:01:02 03:04:05",
+		"FNumber":          "28/10",
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			tags, err := parseExif(sampleTIFF(order))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tags, want) {
+				t.Fatalf("tags = %v, want %v", tags, want)
+			}
+		})
+	}
+}
+
+func TestParseExifBadInput(t *testing.T) {
+	le := binary.LittleEndian
+	valid := sampleTIFF(le)
+
+	withUint32 := func(offset int, v uint32) []byte {
+		data := append([]byte{}, valid...)
+		le.PutUint32(data[offset:], v)
+		return data
+	}
+	withUint16 := func(offset int, v uint16) []byte {
+		data := append([]byte{}, valid...)
+		le.PutUint16(data[offset:], v)
+		return data
+	}
+	// IFD0 mulai di offset 8, entry pertama (Make) di 10, entry terakhir
+	// (pointer Exif) di 10+4*12
+	const (
+		makeEntry    = 10
+		pointerEntry = 10 + 4*12
+	)
+
+	tests := []struct {
+		name string
+		data []byte
+		want map[string]string
+		err  bool
+	}{
+		{"empty", nil, nil, true},
+		{"short header", []byte("II*\x00"), nil, true},
+		{"bad byte order", append([]byte("XX"), valid[2:]...), nil, true},
+		{"bad magic", withUint16(2, 43), nil, true},
+		{"ifd0 offset out of range", withUint32(4, uint32(len(valid))), map[string]string{}, false},
+		{"ifd0 offset overflow", withUint32(4, 0xffffffff), map[string]string{}, false},
+		{"too many entries", withUint16(8, maxIFDEntries+1), map[string]string{}, false},
+		{
+			"entry count past end",
+			withUint16(8, 200),
+			map[string]string{"Make": "Canon", "Orientation": "6", "Artist": "Ab", "DateTimeOriginal": "2023:01:02 03:04:05", "FNumber": "28/10"},
+			false,
+		},
+		{
+			"value offset out of range",
+			withUint32(makeEntry+8, 0xfffffff0),
+			map[string]string{"Orientation": "6", "Artist": "Ab", "DateTimeOriginal": "2023:01:02 03:04:05", "FNumber": "28/10"},
+			false,
+		},
+		{
+			"value count too large",
+			withUint32(makeEntry+4, 1<<20),
+			map[string]string{"Orientation": "6", "Artist": "Ab", "DateTimeOriginal": "2023:01:02 03:04:05", "FNumber": "28/10"},
+			false,
+		},
+		{
+			"unknown type",
+			withUint16(makeEntry+2, 99),
+			map[string]string{"Orientation": "6", "Artist": "Ab", "DateTimeOriginal": "2023:01:02 03:04:05", "FNumber": "28/10"},
+			false,
+		},
+		{
+			"sub-ifd pointer out of range",
+			withUint32(pointerEntry+8, 0x7fffffff),
+			map[string]string{"Make": "Canon", "Orientation": "6", "Artist": "Ab"},
+			false,
+		},
+		{
+			"truncated sub-ifd",
+			valid[:len(valid)-40],
+			map[string]string{"Orientation": "6", "Artist": "Ab"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := parseExif(tt.data)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidImage) {
+					t.Fatalf("parseExif err = %v, want ErrInvalidImage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tags, tt.want) {
+				t.Fatalf("tags = %v, want %v", tags, tt.want)
+			}
+		})
+	}
+}
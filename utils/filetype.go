@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SniffLen adalah jumlah byte awal yang sebaiknya diberikan ke DetectType.
+// Cukup besar supaya header entry "word/" atau "xl/" di ZIP Office ikut terbaca.
+const SniffLen = 4096
+
+var ErrUnsupportedType = errors.New("unsupported file format")
+
+// FileType mendeskripsikan satu format file yang dikenali dari magic byte.
+// Extensions pertama adalah ekstensi kanonik yang dipakai untuk nama object.
+type FileType struct {
+	MimeType   string
+	Extensions []string
+	Match      func(head []byte) bool
+}
+
+// Ext mengembalikan ekstensi kanonik, mis. ".pdf"
+func (t FileType) Ext() string {
+	if len(t.Extensions) == 0 {
+		return ""
+	}
+	return t.Extensions[0]
+}
+
+var (
+	registryMu sync.RWMutex
+	// Dicek berurutan, yang lebih spesifik harus di depan (DOCX sebelum ZIP,
+	// plain text paling akhir), jadi hasil deteksi selalu deterministik
+	registry = []FileType{
+		{MimeType: "application/pdf", Extensions: []string{".pdf"}, Match: prefixMatcher("%PDF-")},
+		{MimeType: "image/png", Extensions: []string{".png"}, Match: prefixMatcher("\x89PNG\r\n\x1a\n")},
+		{MimeType: "image/jpeg", Extensions: []string{".jpg", ".jpeg"}, Match: prefixMatcher("\xff\xd8\xff")},
+		{MimeType: "image/gif", Extensions: []string{".gif"}, Match: prefixMatcher("GIF87a", "GIF89a")},
+		{MimeType: "image/webp", Extensions: []string{".webp"}, Match: riffMatcher("WEBP")},
+		{MimeType: "image/tiff", Extensions: []string{".tiff", ".tif"}, Match: prefixMatcher("II*\x00", "MM\x00*")},
+		{MimeType: "image/heic", Extensions: []string{".heic"}, Match: ftypMatcher("heic", "heix", "heim", "heis", "hevc", "hevx")},
+		{MimeType: "image/heif", Extensions: []string{".heif"}, Match: ftypMatcher("mif1", "msf1")},
+		{MimeType: "video/quicktime", Extensions: []string{".mov"}, Match: ftypMatcher("qt  ")},
+		{MimeType: "video/mp4", Extensions: []string{".mp4", ".m4v"}, Match: ftypMatcher("isom", "iso2", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "M4V ", "dash")},
+		{MimeType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Extensions: []string{".docx"}, Match: zipEntryMatcher("word/")},
+		{MimeType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extensions: []string{".xlsx"}, Match: zipEntryMatcher("xl/")},
+		{MimeType: "application/vnd.openxmlformats-officedocument.presentationml.presentation", Extensions: []string{".pptx"}, Match: zipEntryMatcher("ppt/")},
+		{MimeType: "application/zip", Extensions: []string{".zip"}, Match: prefixMatcher("PK\x03\x04", "PK\x05\x06")},
+		{MimeType: "text/plain", Extensions: []string{".txt"}, Match: isPlainText},
+	}
+)
+
+// RegisterType menambah format baru. Format yang didaftarkan caller dicek
+// sebelum format bawaan, jadi bisa dipakai untuk format yang lebih spesifik
+// (mis. ZIP dengan struktur tertentu).
+func RegisterType(t FileType) error {
+	if t.MimeType == "" || t.Match == nil || len(t.Extensions) == 0 {
+		return errors.New("file type needs mime type, extensions and matcher")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append([]FileType{t}, registry...)
+	return nil
+}
+
+// DetectType mengenali format dari byte awal file (sebaiknya SniffLen byte)
+func DetectType(head []byte) (FileType, error) {
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, t := range registry {
+		if t.Match(head) {
+			return t, nil
+		}
+	}
+	return FileType{}, ErrUnsupportedType
+}
+
+// LookupType mencari format terdaftar berdasarkan mime type
+func LookupType(mimeType string) (FileType, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, t := range registry {
+		if t.MimeType == mimeType {
+			return t, nil
+		}
+	}
+	return FileType{}, errors.New("unsupported mimeType: " + mimeType)
+}
+
+// Allowlist berisi mime type yang diizinkan untuk satu route. Entry boleh
+// berupa wildcard seperti "image/*". Allowlist kosong mengizinkan semua
+// format yang terdaftar.
+type Allowlist []string
+
+func ParseAllowlist(s string) Allowlist {
+	var list Allowlist
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (a Allowlist) Allows(mimeType string) bool {
+	if len(a) == 0 {
+		return true
+	}
+
+	for _, allowed := range a {
+		if allowed == mimeType {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func prefixMatcher(prefixes ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(head, []byte(p)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Container RIFF: "RIFF" <size 4 byte> <form type>
+func riffMatcher(form string) func([]byte) bool {
+	return func(head []byte) bool {
+		return len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == form
+	}
+}
+
+// ISO base media (MP4, HEIF, MOV): box "ftyp" di offset 4 lalu major brand
+func ftypMatcher(brands ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		if len(head) < 12 || string(head[4:8]) != "ftyp" {
+			return false
+		}
+		for _, brand := range brands {
+			if string(head[8:12]) == brand {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Dokumen Office Open XML adalah ZIP dengan entry di bawah folder khas
+// (mis. word/document.xml). Yang dicocokkan nama entry dari local file header,
+// bukan sembarang byte, jadi ZIP biasa berisi "password/x" tidak ikut terdeteksi.
+func zipEntryMatcher(folder string) func([]byte) bool {
+	return func(head []byte) bool {
+		for _, name := range zipEntryNames(head) {
+			if strings.HasPrefix(name, folder) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var zipLocalHeader = []byte("PK\x03\x04")
+
+// Nama entry dari local file header yang muat di head. Entry dengan data
+// descriptor (flag bit 3) tidak menyimpan ukuran di header, jadi header
+// berikutnya dicari dari signature-nya.
+func zipEntryNames(head []byte) []string {
+	var names []string
+	for offset := 0; offset+30 <= len(head) && bytes.HasPrefix(head[offset:], zipLocalHeader); {
+		h := head[offset:]
+		flags := binary.LittleEndian.Uint16(h[6:])
+		compressed := int(binary.LittleEndian.Uint32(h[18:]))
+		nameLen := int(binary.LittleEndian.Uint16(h[26:]))
+		extraLen := int(binary.LittleEndian.Uint16(h[28:]))
+		if 30+nameLen > len(h) {
+			break
+		}
+		names = append(names, string(h[30:30+nameLen]))
+
+		next := offset + 30 + nameLen + extraLen
+		if flags&0x8 != 0 {
+			if next >= len(head) {
+				break
+			}
+			i := bytes.Index(head[next:], zipLocalHeader)
+			if i < 0 {
+				break
+			}
+			offset = next + i
+			continue
+		}
+		offset = next + compressed
+	}
+	return names
+}
+
+// UTF-8 valid tanpa karakter kontrol selain whitespace umum
+func isPlainText(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+
+	// Head bisa terpotong di tengah rune multi-byte
+	for i := 0; i < utf8.UTFMax-1 && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+
+	for _, b := range head {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' {
+			return false
+		}
+		if b == 0x7f {
+			return false
+		}
+	}
+	return true
+}
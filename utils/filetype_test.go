@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// ZIP dengan entry berurutan, method Store pakai header lengkap (tanpa data
+// descriptor) dan Deflate pakai data descriptor seperti kebanyakan writer
+func buildZip(t *testing.T, method uint16, names ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		content := []byte("content of " + name)
+		header := &zip.FileHeader{Name: name, Method: method}
+		if method == zip.Store {
+			header.CompressedSize64 = uint64(len(content))
+			header.UncompressedSize64 = uint64(len(content))
+			w, err := zw.CreateRaw(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(content)
+			continue
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectType(t *testing.T) {
+	const (
+		docx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		xlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		pptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	)
+
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), "image/jpeg"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"riff not webp", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), ""},
+		{"tiff little endian", []byte("II*\x00\x08\x00\x00\x00"), "image/tiff"},
+		{"tiff big endian", []byte("MM\x00*\x00\x00\x00\x08"), "image/tiff"},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), "image/heic"},
+		{"heif", []byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00"), "image/heif"},
+		{"mov", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "video/quicktime"},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00"), "video/mp4"},
+		{"unknown ftyp brand", []byte("\x00\x00\x00\x18ftypabcd\x00\x00\x00\x00"), ""},
+
+		// Office dicek sebelum ZIP biasa
+		{"docx", buildZip(t, zip.Deflate, "[Content_Types].xml", "_rels/.rels", "word/document.xml"), docx},
+		{"xlsx", buildZip(t, zip.Deflate, "[Content_Types].xml", "xl/workbook.xml"), xlsx},
+		{"pptx", buildZip(t, zip.Deflate, "[Content_Types].xml", "ppt/presentation.xml"), pptx},
+		{"docx stored", buildZip(t, zip.Store, "[Content_Types].xml", "word/document.xml"), docx},
+		{"zip", buildZip(t, zip.Deflate, "readme.txt"), "application/zip"},
+		// Nama folder Office muncul di tengah nama entry atau isi file, bukan prefix
+		{"zip with similar names", buildZip(t, zip.Store, "password/x", "myxl/a", "notes/word/b"), "application/zip"},
+		{"empty zip", buildZip(t, zip.Deflate), "application/zip"},
+
+		{"text", []byte("hello, world\r\n\tline two\n"), "text/plain"},
+		{"utf-8 text", []byte("halo dunia \xe2\x9c\x93"), "text/plain"},
+		// Rune multi-byte terpotong di akhir head masih dianggap teks
+		{"truncated rune", []byte("halo \xe2\x9c"), "text/plain"},
+		{"control byte", []byte("hello\x00world"), ""},
+		{"invalid utf-8", []byte("hello \xff\xfe world"), ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectType(tt.head)
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnsupportedType) {
+					t.Fatalf("DetectType = %q, %v, want ErrUnsupportedType", got.MimeType, err)
+				}
+				return
+			}
+			if err != nil || got.MimeType != tt.want {
+				t.Fatalf("DetectType = %q, %v, want %q", got.MimeType, err, tt.want)
+			}
+		})
+	}
+}
+
+// Entry Office di luar SniffLen tidak terbaca, jadi jatuh ke ZIP biasa
+func TestDetectTypeSniffLen(t *testing.T) {
+	var names []string
+	for i := 0; i < 100; i++ {
+		names = append(names, "media/padding-entry-with-a-long-name.bin")
+	}
+	names = append(names, "word/document.xml")
+
+	got, err := DetectType(buildZip(t, zip.Store, names...))
+	if err != nil || got.MimeType != "application/zip" {
+		t.Fatalf("DetectType = %q, %v, want application/zip", got.MimeType, err)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	tests := []struct {
+		list     string
+		mimeType string
+		want     bool
+	}{
+		{"", "application/pdf", true},
+		{"application/pdf", "application/pdf", true},
+		{"application/pdf, image/png", "image/png", true},
+		{"application/pdf", "image/png", false},
+		{"image/*", "image/jpeg", true},
+		{"image/*", "video/mp4", false},
+		{"image/*", "imagex/png", false},
+	}
+
+	for _, tt := range tests {
+		if got := ParseAllowlist(tt.list).Allows(tt.mimeType); got != tt.want {
+			t.Errorf("ParseAllowlist(%q).Allows(%q) = %v, want %v", tt.list, tt.mimeType, got, tt.want)
+		}
+	}
+}
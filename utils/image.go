@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+)
+
+var (
+	ErrImageTooLarge = errors.New("image dimensions exceed limit")
+	ErrInvalidImage  = errors.New("invalid image data")
+)
+
+// DecodeImage decode PNG/JPEG dari r. Ukuran dicek dulu dari header, jadi
+// file kecil yang mengaku berukuran raksasa ditolak sebelum piksel dialokasi.
+func DecodeImage(r io.Reader, maxPixels int64) (image.Image, string, error) {
+	var head bytes.Buffer
+	cfg, format, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, "", ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&head, r))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	return img, format, nil
+}
+
+// EncodeImage menulis img dalam format "png" atau "jpeg"
+func EncodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "png":
+		return png.Encode(w, img)
+	case "jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	default:
+		return fmt.Errorf("unsupported image format: %s", format)
+	}
+}
+
+// FitSize menghitung ukuran baru supaya gambar w x h muat di kotak
+// limit x limit dengan rasio tetap. Gambar yang sudah lebih kecil tidak diperbesar.
+func FitSize(w int, h int, limit int) (int, int) {
+	if w <= limit && h <= limit {
+		return w, h
+	}
+	if w >= h {
+		return limit, clampDimension(int(math.Round(float64(h) * float64(limit) / float64(w))))
+	}
+	return clampDimension(int(math.Round(float64(w) * float64(limit) / float64(h)))), limit
+}
+
+func clampDimension(v int) int {
+	if v < 1 {
+		return 1
+	}
+	return v
+}
+
+// Resize mengubah ukuran src jadi w x h dengan filter triangle yang
+// diperlebar sesuai rasio skala, jadi downscale merata-rata area sumber
+// (tidak aliasing) dan upscale jadi bilinear. Dihitung di ruang
+// premultiplied alpha supaya tepi transparan tidak jadi gelap.
+func Resize(src image.Image, w int, h int) *image.RGBA {
+	b := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok || b.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	}
+
+	// Dua pass terpisah: horizontal dulu lalu vertikal
+	tmp := image.NewRGBA(image.Rect(0, 0, w, b.Dy()))
+	resampleRows(tmp, rgba, resampleWeights(w, b.Dx()))
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	resampleColumns(dst, tmp, resampleWeights(h, b.Dy()))
+	return dst
+}
+
+type resampleWeight struct {
+	index  int
+	weight float64
+}
+
+// Bobot sumber untuk setiap piksel tujuan di satu dimensi
+func resampleWeights(dstLen int, srcLen int) [][]resampleWeight {
+	scale := float64(srcLen) / float64(dstLen)
+	support := math.Max(scale, 1)
+
+	weights := make([][]resampleWeight, dstLen)
+	for i := range weights {
+		center := (float64(i)+0.5)*scale - 0.5
+		lo := int(math.Floor(center - support))
+		hi := int(math.Ceil(center + support))
+
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			d := math.Abs(float64(j)-center) / support
+			if d >= 1 {
+				continue
+			}
+			index := j
+			if index < 0 {
+				index = 0
+			} else if index >= srcLen {
+				index = srcLen - 1
+			}
+			weights[i] = append(weights[i], resampleWeight{index: index, weight: 1 - d})
+			sum += 1 - d
+		}
+		for k := range weights[i] {
+			weights[i][k].weight /= sum
+		}
+	}
+	return weights
+}
+
+func resampleRows(dst *image.RGBA, src *image.RGBA, weights [][]resampleWeight) {
+	for y := 0; y < src.Rect.Dy(); y++ {
+		srcRow := src.Pix[y*src.Stride:]
+		dstRow := dst.Pix[y*dst.Stride:]
+		for x, ws := range weights {
+			var r, g, b, a float64
+			for _, w := range ws {
+				p := srcRow[w.index*4:]
+				r += float64(p[0]) * w.weight
+				g += float64(p[1]) * w.weight
+				b += float64(p[2]) * w.weight
+				a += float64(p[3]) * w.weight
+			}
+			putPixel(dstRow[x*4:], r, g, b, a)
+		}
+	}
+}
+
+func resampleColumns(dst *image.RGBA, src *image.RGBA, weights [][]resampleWeight) {
+	for x := 0; x < src.Rect.Dx(); x++ {
+		for y, ws := range weights {
+			var r, g, b, a float64
+			for _, w := range ws {
+				p := src.Pix[w.index*src.Stride+x*4:]
+				r += float64(p[0]) * w.weight
+				g += float64(p[1]) * w.weight
+				b += float64(p[2]) * w.weight
+				a += float64(p[3]) * w.weight
+			}
+			putPixel(dst.Pix[y*dst.Stride+x*4:], r, g, b, a)
+		}
+	}
+}
+
+func putPixel(p []byte, r float64, g float64, b float64, a float64) {
+	p[0] = clampByte(r)
+	p[1] = clampByte(g)
+	p[2] = clampByte(b)
+	p[3] = clampByte(a)
+	// Premultiplied: warna tidak boleh lebih besar dari alpha
+	for i := 0; i < 3; i++ {
+		if p[i] > p[3] {
+			p[i] = p[3]
+		}
+	}
+}
+
+func clampByte(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
+
+// Fit menentukan cara gambar dimasukkan ke kotak w x h
+type Fit string
+
+const (
+	// Muat di dalam kotak dengan rasio tetap, tidak diperbesar
+	FitContain Fit = "contain"
+	// Tutup seluruh kotak dengan rasio tetap, sisanya di-crop dari tengah
+	FitCover Fit = "cover"
+	// Paksa jadi persis w x h tanpa menjaga rasio
+	FitFill Fit = "fill"
+)
+
+func ParseFit(s string) (Fit, error) {
+	switch fit := Fit(strings.ToLower(s)); fit {
+	case "":
+		return FitContain, nil
+	case FitContain, FitCover, FitFill:
+		return fit, nil
+	default:
+		return "", fmt.Errorf("unknown fit: %q", s)
+	}
+}
+
+// Transform mengubah ukuran img ke kotak w x h sesuai fit. w atau h boleh 0,
+// artinya mengikuti rasio dari sisi yang lain (cover dan fill lalu
+// diperlakukan seperti contain).
+func Transform(img image.Image, w int, h int, fit Fit) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+
+	if w == 0 || h == 0 {
+		fit = FitContain
+	}
+
+	switch fit {
+	case FitFill:
+		return Resize(img, w, h)
+	case FitCover:
+		// Crop sumber ke rasio kotak dulu, baru di-resize
+		scale := math.Max(float64(w)/float64(srcW), float64(h)/float64(srcH))
+		cropW := clampDimension(int(math.Round(float64(w) / scale)))
+		cropH := clampDimension(int(math.Round(float64(h) / scale)))
+		if cropW > srcW {
+			cropW = srcW
+		}
+		if cropH > srcH {
+			cropH = srcH
+		}
+		x0 := b.Min.X + (srcW-cropW)/2
+		y0 := b.Min.Y + (srcH-cropH)/2
+		return Resize(subImage(img, image.Rect(x0, y0, x0+cropW, y0+cropH)), w, h)
+	default:
+		scale := 1.0
+		if w > 0 {
+			scale = math.Min(scale, float64(w)/float64(srcW))
+		}
+		if h > 0 {
+			scale = math.Min(scale, float64(h)/float64(srcH))
+		}
+		outW := clampDimension(int(math.Round(float64(srcW) * scale)))
+		outH := clampDimension(int(math.Round(float64(srcH) * scale)))
+		return Resize(img, outW, outH)
+	}
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, r.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,413 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"strconv"
+)
+
+const (
+	// Batas total segmen/chunk sebelum data gambar yang di-buffer saat membaca header
+	maxImageHeaderSize = 16 << 20
+	// Batas chunk eXIf PNG yang mau di-parse, sisanya dibuang tanpa dibaca
+	maxExifChunkSize = 1 << 20
+)
+
+var (
+	jpegExifHeader = []byte("Exif\x00\x00")
+	jpegICCHeader  = []byte("ICC_PROFILE\x00")
+	pngSignature   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// Chunk PNG yang dibuang: teks (bisa berisi XMP), EXIF dan waktu modifikasi
+var pngDroppedChunks = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+// ImageMetadata berisi info dari metadata gambar yang dibuang StripImageMetadata
+type ImageMetadata struct {
+	// Orientation EXIF 1-8, 1 berarti gambar sudah tegak
+	Orientation int
+	// Tag EXIF yang dikenali (nama -> nilai), tanpa Orientation
+	Tags map[string]string
+}
+
+func (m *ImageMetadata) setExif(data []byte) {
+	tags, err := parseExif(data)
+	if err != nil {
+		return
+	}
+	if o, err := strconv.Atoi(tags["Orientation"]); err == nil && o >= 1 && o <= 8 {
+		m.Orientation = o
+	}
+	delete(tags, "Orientation")
+	m.Tags = tags
+}
+
+// StripImageMetadata mengembalikan reader berisi gambar r (format "jpeg" atau
+// "png") tanpa metadata. JPEG: segmen EXIF, XMP, IPTC, komentar dan APPn
+// vendor dibuang, JFIF, ICC profile dan Adobe dipertahankan. PNG: chunk
+// teks, eXIf dan tIME dibuang. Data setelah akhir gambar ikut dibuang.
+// Piksel tidak di-decode, jadi reader tetap streaming. Header dibaca saat
+// fungsi ini dipanggil, jadi ImageMetadata sudah terisi sebelum reader dibaca.
+func StripImageMetadata(r io.Reader, format string) (io.Reader, *ImageMetadata, error) {
+	meta := &ImageMetadata{Orientation: 1, Tags: map[string]string{}}
+	br := bufio.NewReader(r)
+
+	switch format {
+	case "jpeg":
+		s := &jpegStripper{r: br, meta: meta}
+		return s, meta, s.readHeader()
+	case "png":
+		s := &pngStripper{r: br, meta: meta}
+		return s, meta, s.readHeader()
+	default:
+		return nil, nil, fmt.Errorf("unsupported image format: %s", format)
+	}
+}
+
+// EOF di tengah struktur gambar berarti file terpotong
+func truncatedImage(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of image", ErrInvalidImage)
+	}
+	return err
+}
+
+type jpegStripper struct {
+	r       *bufio.Reader
+	meta    *ImageMetadata
+	pending []byte
+	// Sedang di entropy-coded data setelah SOS
+	scan     bool
+	done     bool
+	exifSeen bool
+}
+
+// Baca semua segmen sampai scan pertama. EXIF selalu ada di sini.
+func (s *jpegStripper) readHeader() error {
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(s.r, soi); err != nil {
+		return truncatedImage(err)
+	}
+	if soi[0] != 0xff || soi[1] != 0xd8 {
+		return fmt.Errorf("%w: missing jpeg SOI marker", ErrInvalidImage)
+	}
+	s.pending = soi
+
+	for !s.scan && !s.done {
+		if len(s.pending) > maxImageHeaderSize {
+			return fmt.Errorf("%w: jpeg header too large", ErrInvalidImage)
+		}
+		if err := s.nextSegment(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Baca kode marker setelah 0xff, byte 0xff tambahan adalah fill
+func (s *jpegStripper) readMarker() (byte, error) {
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return 0, truncatedImage(err)
+		}
+		if b != 0xff {
+			return b, nil
+		}
+	}
+}
+
+func (s *jpegStripper) nextSegment() error {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return truncatedImage(err)
+	}
+	if b != 0xff {
+		return fmt.Errorf("%w: expected jpeg marker", ErrInvalidImage)
+	}
+	marker, err := s.readMarker()
+	if err != nil {
+		return err
+	}
+	return s.segment(marker)
+}
+
+// Proses satu segmen: diteruskan ke pending atau dibuang
+func (s *jpegStripper) segment(marker byte) error {
+	switch {
+	case marker == 0xd9:
+		// EOI
+		s.pending = append(s.pending, 0xff, marker)
+		s.done = true
+		return nil
+	case marker >= 0xd0 && marker <= 0xd7, marker == 0x01:
+		// RSTn dan TEM tidak punya panjang
+		s.pending = append(s.pending, 0xff, marker)
+		return nil
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(s.r, length[:]); err != nil {
+		return truncatedImage(err)
+	}
+	n := int(binary.BigEndian.Uint16(length[:]))
+	if n < 2 {
+		return fmt.Errorf("%w: bad jpeg segment length", ErrInvalidImage)
+	}
+	payload := make([]byte, n-2)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		return truncatedImage(err)
+	}
+
+	if marker == 0xe1 && !s.exifSeen && bytes.HasPrefix(payload, jpegExifHeader) {
+		s.exifSeen = true
+		s.meta.setExif(payload[len(jpegExifHeader):])
+	}
+	if keepJPEGSegment(marker, payload) {
+		s.pending = append(s.pending, 0xff, marker, length[0], length[1])
+		s.pending = append(s.pending, payload...)
+	}
+	if marker == 0xda {
+		s.scan = true
+	}
+	return nil
+}
+
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == 0xe0, marker == 0xee:
+		// JFIF dan Adobe (transform warna CMYK/YCCK)
+		return true
+	case marker == 0xe2:
+		return bytes.HasPrefix(payload, jpegICCHeader)
+	case marker >= 0xe1 && marker <= 0xef, marker == 0xfe:
+		// EXIF, XMP, IPTC, komentar dan APPn vendor lain
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *jpegStripper) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.pending) > 0 {
+			c := copy(p[n:], s.pending)
+			s.pending = s.pending[c:]
+			n += c
+			continue
+		}
+		if s.done {
+			break
+		}
+		if !s.scan {
+			if err := s.nextSegment(); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		// Entropy-coded data diteruskan sampai ketemu marker yang bukan
+		// byte stuffing (0xff00) atau RSTn
+		buf, err := s.r.Peek(1)
+		if err != nil {
+			return n, truncatedImage(err)
+		}
+		buf, _ = s.r.Peek(s.r.Buffered())
+		if i := bytes.IndexByte(buf, 0xff); i != 0 {
+			if i < 0 {
+				i = len(buf)
+			}
+			c := copy(p[n:], buf[:i])
+			s.r.Discard(c)
+			n += c
+			continue
+		}
+
+		s.r.Discard(1)
+		marker, err := s.readMarker()
+		if err != nil {
+			return n, err
+		}
+		if marker == 0x00 || (marker >= 0xd0 && marker <= 0xd7) {
+			s.pending = append(s.pending, 0xff, marker)
+			continue
+		}
+		s.scan = false
+		if err := s.segment(marker); err != nil {
+			return n, err
+		}
+	}
+
+	if n == 0 && s.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+type pngStripper struct {
+	r       *bufio.Reader
+	meta    *ImageMetadata
+	pending []byte
+	// Sisa isi chunk (termasuk CRC) yang diteruskan langsung dari r
+	remaining int64
+	idat      bool
+	done      bool
+	exifSeen  bool
+}
+
+// Baca chunk sampai IDAT pertama. eXIf setelah IDAT tetap dibuang, tapi
+// isinya tidak dipakai untuk orientasi.
+func (s *pngStripper) readHeader() error {
+	sig := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(s.r, sig); err != nil {
+		return truncatedImage(err)
+	}
+	if !bytes.Equal(sig, pngSignature) {
+		return fmt.Errorf("%w: missing png signature", ErrInvalidImage)
+	}
+	s.pending = sig
+
+	for !s.idat && !s.done {
+		if len(s.pending) > maxImageHeaderSize {
+			return fmt.Errorf("%w: png header too large", ErrInvalidImage)
+		}
+		if err := s.nextChunk(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *pngStripper) nextChunk() error {
+	var header [8]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		return truncatedImage(err)
+	}
+	length := int64(binary.BigEndian.Uint32(header[:4]))
+	if length > 1<<31-1 {
+		return fmt.Errorf("%w: bad png chunk length", ErrInvalidImage)
+	}
+	typ := string(header[4:])
+
+	if pngDroppedChunks[typ] {
+		if typ == "eXIf" && !s.exifSeen && !s.idat && length <= maxExifChunkSize {
+			s.exifSeen = true
+			data := make([]byte, length+4)
+			if _, err := io.ReadFull(s.r, data); err != nil {
+				return truncatedImage(err)
+			}
+			s.meta.setExif(data[:length])
+			return nil
+		}
+		if _, err := io.CopyN(io.Discard, s.r, length+4); err != nil {
+			return truncatedImage(err)
+		}
+		return nil
+	}
+
+	s.pending = append(s.pending, header[:]...)
+	switch typ {
+	case "IDAT":
+		s.idat = true
+	case "IEND":
+		s.done = true
+	}
+	if s.idat || s.done {
+		s.remaining = length + 4
+		return nil
+	}
+
+	// Chunk sebelum IDAT kecil, di-buffer supaya header bisa dibaca sampai habis
+	start := len(s.pending)
+	s.pending = append(s.pending, make([]byte, length+4)...)
+	if _, err := io.ReadFull(s.r, s.pending[start:]); err != nil {
+		return truncatedImage(err)
+	}
+	return nil
+}
+
+func (s *pngStripper) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.pending) > 0 {
+			c := copy(p[n:], s.pending)
+			s.pending = s.pending[c:]
+			n += c
+			continue
+		}
+		if s.remaining > 0 {
+			want := len(p) - n
+			if int64(want) > s.remaining {
+				want = int(s.remaining)
+			}
+			c, err := s.r.Read(p[n : n+want])
+			n += c
+			s.remaining -= int64(c)
+			if err != nil {
+				return n, truncatedImage(err)
+			}
+			continue
+		}
+		if s.done {
+			break
+		}
+		if err := s.nextChunk(); err != nil {
+			return n, err
+		}
+	}
+
+	if n == 0 && s.done && s.remaining == 0 && len(s.pending) == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// ApplyOrientation memutar/membalik img sesuai nilai Orientation EXIF (1-8)
+// sehingga hasilnya tegak tanpa perlu metadata orientasi lagi
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		// 5-8 menukar lebar dan tinggi
+		dstW, dstH = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // flip horizontal
+				sx, sy = w-1-x, y
+			case 3: // putar 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // flip vertikal
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // putar 90 searah jarum jam
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // putar 90 berlawanan jarum jam
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,331 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], typ)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func exifWithOrientation(o uint16) []byte {
+	return buildTIFF(binary.BigEndian, []tiffEntry{
+		asciiEntry(0x010f, "Canon"),
+		shortEntry(binary.BigEndian, 0x0112, o),
+	}, nil)
+}
+
+func testImage() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x * 16), uint8(y * 32), 128, 255})
+		}
+	}
+	return img
+}
+
+func stripAll(t *testing.T, data []byte, format string) ([]byte, *ImageMetadata, error) {
+	t.Helper()
+	r, meta, err := StripImageMetadata(bytes.NewReader(data), format)
+	if err != nil {
+		return nil, meta, err
+	}
+	// Baca per byte supaya batas segmen/chunk di tengah Read ikut teruji
+	out, err := io.ReadAll(iotest.OneByteReader(r))
+	return out, meta, err
+}
+
+func TestStripImageMetadataJPEG(t *testing.T) {
+	jfif := jpegSegment(0xe0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	icc := jpegSegment(0xe2, []byte("ICC_PROFILE\x00\x01\x01profile"))
+	adobe := jpegSegment(0xee, []byte("Adobe\x00\x64\x00\x00\x00\x00\x01"))
+	dqt := jpegSegment(0xdb, bytes.Repeat([]byte{1}, 65))
+	sof := jpegSegment(0xc0, []byte{8, 0, 1, 0, 1, 1, 1, 0x11, 0})
+	dht := jpegSegment(0xc4, bytes.Repeat([]byte{2}, 20))
+	sos := jpegSegment(0xda, []byte{1, 1, 0, 0, 0x3f, 0})
+	// Entropy data dengan byte stuffing (ff00), restart marker dan fill byte
+	scan1 := []byte{0x12, 0xff, 0x00, 0x34, 0xff, 0xd0, 0x56, 0xff, 0xff, 0xd1, 0x78, 0xff, 0x00}
+	scan2 := []byte{0x9a, 0xff, 0x00, 0xff, 0xd7, 0xbc}
+	eoi := []byte{0xff, 0xd9}
+
+	in := concat(
+		[]byte{0xff, 0xd8},
+		jfif,
+		jpegSegment(0xe1, append([]byte("Exif\x00\x00"), exifWithOrientation(6)...)),
+		jpegSegment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")),
+		icc,
+		jpegSegment(0xe2, []byte("MPF\x00vendor")),
+		jpegSegment(0xed, []byte("Photoshop 3.0\x008BIM")),
+		adobe,
+		jpegSegment(0xfe, []byte("comment")),
+		jpegSegment(0xe9, []byte("vendor")),
+		dqt, sof, dht, sos, scan1,
+		// Scan kedua (progressive): segmen di antara scan tetap diproses
+		jpegSegment(0xfe, []byte("between scans")),
+		dht, sos, scan2,
+		eoi,
+		[]byte("trailing data after EOI"),
+	)
+	want := concat(
+		[]byte{0xff, 0xd8},
+		jfif, icc, adobe, dqt, sof, dht, sos,
+		[]byte{0x12, 0xff, 0x00, 0x34, 0xff, 0xd0, 0x56, 0xff, 0xd1, 0x78, 0xff, 0x00},
+		dht, sos, scan2,
+		eoi,
+	)
+
+	out, meta, err := stripAll(t, in, "jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, want) {
+		t.Fatalf("stripped jpeg\n got % x\nwant % x", out, want)
+	}
+	if meta.Orientation != 6 || meta.Tags["Make"] != "Canon" {
+		t.Fatalf("meta = %+v", meta)
+	}
+	if _, ok := meta.Tags["Orientation"]; ok {
+		t.Fatal("Orientation should not be in Tags")
+	}
+}
+
+func TestStripImageMetadataRealImages(t *testing.T) {
+	var jpegBuf, pngBuf bytes.Buffer
+	if err := jpeg.Encode(&jpegBuf, testImage(), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&pngBuf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	jpegData, pngData := jpegBuf.Bytes(), pngBuf.Bytes()
+
+	// Sisipkan metadata setelah SOI / IHDR
+	const ihdrEnd = 8 + 12 + 13
+	tests := []struct {
+		format string
+		clean  []byte
+		dirty  []byte
+	}{
+		{"jpeg", jpegData, concat(
+			jpegData[:2],
+			jpegSegment(0xe1, append([]byte("Exif\x00\x00"), exifWithOrientation(1)...)),
+			jpegSegment(0xfe, []byte("comment")),
+			jpegData[2:],
+		)},
+		{"png", pngData, concat(
+			pngData[:ihdrEnd],
+			pngChunk("tEXt", []byte("Comment\x00hello")),
+			pngChunk("eXIf", exifWithOrientation(1)),
+			pngData[ihdrEnd:],
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, meta, err := stripAll(t, tt.dirty, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, tt.clean) {
+				t.Fatalf("stripped %s differs from the original encoding", tt.format)
+			}
+			if meta.Orientation != 1 || meta.Tags["Make"] != "Canon" {
+				t.Fatalf("meta = %+v", meta)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(out)); err != nil {
+				t.Fatalf("decode stripped %s: %v", tt.format, err)
+			}
+		})
+	}
+}
+
+func TestStripImageMetadataPNGChunkOrder(t *testing.T) {
+	sig := []byte("\x89PNG\r\n\x1a\n")
+	ihdr := pngChunk("IHDR", []byte{0, 0, 0, 1, 0, 0, 0, 1, 8, 6, 0, 0, 0})
+	iccp := pngChunk("iCCP", []byte("icc\x00\x00data"))
+	plte := pngChunk("PLTE", []byte{0, 0, 0})
+	idat1 := pngChunk("IDAT", bytes.Repeat([]byte{0xaa}, 100))
+	idat2 := pngChunk("IDAT", bytes.Repeat([]byte{0xbb}, 10))
+	iend := pngChunk("IEND", nil)
+
+	tests := []struct {
+		name        string
+		in          []byte
+		want        []byte
+		orientation int
+	}{
+		{
+			"metadata before IDAT",
+			concat(sig, ihdr, pngChunk("tEXt", []byte("Author\x00me")), iccp, pngChunk("eXIf", exifWithOrientation(3)), plte, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x/>")), idat1, idat2, iend),
+			concat(sig, ihdr, iccp, plte, idat1, idat2, iend),
+			3,
+		},
+		{
+			"metadata between and after IDAT",
+			concat(sig, ihdr, idat1, pngChunk("tIME", []byte{7, 231, 1, 2, 3, 4, 5}), idat2, pngChunk("zTXt", []byte("k\x00\x00x")), iend),
+			concat(sig, ihdr, idat1, idat2, iend),
+			1,
+		},
+		{
+			// Orientasi hanya diambil dari eXIf sebelum IDAT
+			"eXIf after IDAT",
+			concat(sig, ihdr, idat1, pngChunk("eXIf", exifWithOrientation(6)), iend),
+			concat(sig, ihdr, idat1, iend),
+			1,
+		},
+		{
+			"only the first eXIf counts",
+			concat(sig, ihdr, pngChunk("eXIf", exifWithOrientation(8)), pngChunk("eXIf", exifWithOrientation(2)), idat1, iend),
+			concat(sig, ihdr, idat1, iend),
+			8,
+		},
+		{
+			"trailing data after IEND",
+			concat(sig, ihdr, idat1, iend, []byte("appended zip")),
+			concat(sig, ihdr, idat1, iend),
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, meta, err := stripAll(t, tt.in, "png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, tt.want) {
+				t.Fatalf("stripped png\n got % x\nwant % x", out, tt.want)
+			}
+			if meta.Orientation != tt.orientation {
+				t.Fatalf("orientation = %d, want %d", meta.Orientation, tt.orientation)
+			}
+		})
+	}
+}
+
+func TestStripImageMetadataTruncated(t *testing.T) {
+	var jpegBuf, pngBuf bytes.Buffer
+	jpeg.Encode(&jpegBuf, testImage(), nil)
+	png.Encode(&pngBuf, testImage())
+
+	inputs := map[string][]byte{
+		"jpeg": concat(jpegBuf.Bytes()[:2], jpegSegment(0xe1, append([]byte("Exif\x00\x00"), exifWithOrientation(6)...)), jpegBuf.Bytes()[2:]),
+		"png":  pngBuf.Bytes(),
+	}
+
+	for format, data := range inputs {
+		// Setiap potongan sebelum akhir gambar harus gagal, bukan menghasilkan gambar terpotong
+		for n := 0; n < len(data); n++ {
+			_, _, err := stripAll(t, data[:n], format)
+			if !errors.Is(err, ErrInvalidImage) {
+				t.Fatalf("%s truncated at %d/%d: err = %v, want ErrInvalidImage", format, n, len(data), err)
+			}
+		}
+	}
+}
+
+func TestStripImageMetadataInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+	}{
+		{"jpeg without SOI", "jpeg", []byte("\x89PNG\r\n\x1a\n")},
+		{"jpeg garbage between segments", "jpeg", []byte{0xff, 0xd8, 0x00, 0x01}},
+		{"jpeg bad segment length", "jpeg", []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x01}},
+		{"png without signature", "png", []byte{0xff, 0xd8, 0xff}},
+		{"png bad chunk length", "png", concat([]byte("\x89PNG\r\n\x1a\n"), []byte{0xff, 0xff, 0xff, 0xff}, []byte("IHDR"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := stripAll(t, tt.data, tt.format); !errors.Is(err, ErrInvalidImage) {
+				t.Fatalf("err = %v, want ErrInvalidImage", err)
+			}
+		})
+	}
+
+	if _, _, err := StripImageMetadata(bytes.NewReader(nil), "gif"); err == nil {
+		t.Fatal("unsupported format should fail")
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// Sumber 3x2:
+	//   a b c
+	//   d e f
+	const a, b, c, d, e, f = 10, 20, 30, 40, 50, 60
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i, v := range []uint8{a, b, c, d, e, f} {
+		src.Set(i%3, i/3, color.NRGBA{v, 0, 0, 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{0, [][]uint8{{a, b, c}, {d, e, f}}},
+		{1, [][]uint8{{a, b, c}, {d, e, f}}},
+		{2, [][]uint8{{c, b, a}, {f, e, d}}},
+		{3, [][]uint8{{f, e, d}, {c, b, a}}},
+		{4, [][]uint8{{d, e, f}, {a, b, c}}},
+		{5, [][]uint8{{a, d}, {b, e}, {c, f}}},
+		{6, [][]uint8{{d, a}, {e, b}, {f, c}}},
+		{7, [][]uint8{{f, c}, {e, b}, {d, a}}},
+		{8, [][]uint8{{c, f}, {b, e}, {a, d}}},
+		{9, [][]uint8{{a, b, c}, {d, e, f}}},
+	}
+
+	for _, tt := range tests {
+		got := ApplyOrientation(src, tt.orientation)
+		bounds := got.Bounds()
+		if bounds.Dx() != len(tt.want[0]) || bounds.Dy() != len(tt.want) {
+			t.Fatalf("orientation %d: size %dx%d, want %dx%d", tt.orientation, bounds.Dx(), bounds.Dy(), len(tt.want[0]), len(tt.want))
+		}
+		for y, row := range tt.want {
+			for x, v := range row {
+				r, _, _, _ := got.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				if uint8(r>>8) != v {
+					t.Fatalf("orientation %d: pixel (%d,%d) = %d, want %d", tt.orientation, x, y, r>>8, v)
+				}
+			}
+		}
+	}
+
+	// Bounds yang tidak mulai dari 0 (mis. hasil SubImage) tetap diputar dengan benar
+	sub := src.SubImage(image.Rect(1, 0, 3, 2))
+	got := ApplyOrientation(sub, 6)
+	want := [][]uint8{{e, b}, {f, c}}
+	for y, row := range want {
+		for x, v := range row {
+			if r, _, _, _ := got.At(x, y).RGBA(); uint8(r>>8) != v {
+				t.Fatalf("subimage: pixel (%d,%d) = %d, want %d", x, y, r>>8, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NameScheme menentukan bentuk {id} di nama object
+type NameScheme string
+
+const (
+	// String base62 acak sepanjang Namer.Length
+	NameRandom NameScheme = "random"
+	// ULID 26 karakter, urut berdasarkan waktu
+	NameULID NameScheme = "ulid"
+	// UUID versi 7 (RFC 9562), urut berdasarkan waktu
+	NameUUIDv7 NameScheme = "uuidv7"
+)
+
+const (
+	DefaultNameLength   = 128
+	DefaultNameTemplate = "{id}{ext}"
+)
+
+var ErrInvalidNameTemplate = errors.New("invalid object name template")
+
+func ParseNameScheme(s string) (NameScheme, error) {
+	switch scheme := NameScheme(strings.ToLower(strings.TrimSpace(s))); scheme {
+	case NameRandom, NameULID, NameUUIDv7:
+		return scheme, nil
+	default:
+		return "", fmt.Errorf("unknown name scheme: %q", s)
+	}
+}
+
+// Namer membuat nama object baru dari template. Placeholder yang dikenal:
+// {id}, {ext}, {tenant}, {yyyy}, {mm}, {dd}. Template wajib memuat {id}.
+type Namer struct {
+	Scheme   NameScheme
+	Length   int
+	Template string
+}
+
+// NameVars berisi nilai placeholder selain {id}
+type NameVars struct {
+	Tenant string
+	Ext    string
+	Time   time.Time
+}
+
+func (n Namer) Validate() error {
+	hasID := false
+	err := walkTemplate(n.Template, func(literal string, placeholder string) error {
+		switch placeholder {
+		case "", "ext", "tenant", "yyyy", "mm", "dd":
+		case "id":
+			hasID = true
+		default:
+			return fmt.Errorf("%w: unknown placeholder {%s}", ErrInvalidNameTemplate, placeholder)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !hasID {
+		return fmt.Errorf("%w: {id} is required", ErrInvalidNameTemplate)
+	}
+	if n.Scheme == NameRandom && n.Length <= 0 {
+		return fmt.Errorf("%w: length must be positive", ErrInvalidNameTemplate)
+	}
+	return nil
+}
+
+// Name membuat nama object baru. Hasilnya sudah dinormalisasi dengan
+// NormalizeObjectPath, tenant tidak boleh mengandung slash.
+func (n Namer) Name(vars NameVars) (string, error) {
+	if err := n.Validate(); err != nil {
+		return "", err
+	}
+	if vars.Tenant == "" && strings.Contains(n.Template, "{tenant}") {
+		return "", fmt.Errorf("%w: tenant is required", ErrInvalidObjectPath)
+	}
+	if strings.Contains(vars.Tenant, "/") {
+		return "", fmt.Errorf("%w: tenant must be a single path segment", ErrInvalidObjectPath)
+	}
+
+	id, err := n.newID(vars.Time)
+	if err != nil {
+		return "", err
+	}
+
+	t := vars.Time.UTC()
+	var b strings.Builder
+	walkTemplate(n.Template, func(literal string, placeholder string) error {
+		b.WriteString(literal)
+		switch placeholder {
+		case "id":
+			b.WriteString(id)
+		case "ext":
+			b.WriteString(vars.Ext)
+		case "tenant":
+			b.WriteString(vars.Tenant)
+		case "yyyy":
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case "mm":
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case "dd":
+			fmt.Fprintf(&b, "%02d", t.Day())
+		}
+		return nil
+	})
+
+	return NormalizeObjectPath(b.String())
+}
+
+func (n Namer) newID(t time.Time) (string, error) {
+	switch n.Scheme {
+	case NameULID:
+		return NewULID(t)
+	case NameUUIDv7:
+		return NewUUIDv7(t)
+	default:
+		return RandomString(n.Length)
+	}
+}
+
+// Panggil fn untuk setiap potongan literal diikuti placeholder (kosong untuk
+// literal terakhir)
+func walkTemplate(template string, fn func(literal string, placeholder string) error) error {
+	for {
+		open := strings.IndexByte(template, '{')
+		if open < 0 {
+			if strings.IndexByte(template, '}') >= 0 {
+				return fmt.Errorf("%w: unbalanced }", ErrInvalidNameTemplate)
+			}
+			return fn(template, "")
+		}
+
+		end := strings.IndexByte(template[open:], '}')
+		if end < 0 {
+			return fmt.Errorf("%w: unbalanced {", ErrInvalidNameTemplate)
+		}
+		end += open
+
+		if err := fn(template[:open], template[open+1:end]); err != nil {
+			return err
+		}
+		template = template[end+1:]
+	}
+}
+
+const base62 = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// RandomString membuat string base62 sepanjang n dari crypto/rand.
+// Byte >= 248 dibuang supaya distribusi karakternya rata.
+func RandomString(n int) (string, error) {
+	out := make([]byte, 0, n)
+	buf := make([]byte, n+n/4+1)
+	for len(out) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if b >= 248 {
+				continue
+			}
+			out = append(out, base62[b%62])
+			if len(out) == n {
+				break
+			}
+		}
+	}
+	return string(out), nil
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID membuat ULID: 48 bit timestamp milidetik + 80 bit random,
+// di-encode Crockford base32 jadi 26 karakter
+func NewULID(t time.Time) (string, error) {
+	var b [16]byte
+	putUnixMilli(b[:6], t)
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+
+	// 26 karakter x 5 bit = 130 bit, dua bit teratas selalu nol
+	out := make([]byte, 26)
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
+
+// NewUUIDv7 membuat UUID versi 7: 48 bit timestamp milidetik, sisanya random
+// kecuali bit version dan variant
+func NewUUIDv7(t time.Time) (string, error) {
+	var b [16]byte
+	putUnixMilli(b[:6], t)
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+func putUnixMilli(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRandomString(t *testing.T) {
+	for _, n := range []int{0, 1, 16, 128, 1000} {
+		s, err := RandomString(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != n {
+			t.Fatalf("RandomString(%d) length = %d", n, len(s))
+		}
+		if strings.Trim(s, base62) != "" {
+			t.Fatalf("RandomString(%d) = %q has characters outside base62", n, s)
+		}
+	}
+
+	a, _ := RandomString(32)
+	b, _ := RandomString(32)
+	if a == b {
+		t.Fatal("RandomString returned the same value twice")
+	}
+}
+
+var ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+func TestNewULID(t *testing.T) {
+	tests := []struct {
+		time   time.Time
+		prefix string
+	}{
+		{time.UnixMilli(0), "0000000000"},
+		// Contoh dari spesifikasi ULID
+		{time.UnixMilli(1469918176385), "01ARYZ6S41"},
+		{time.UnixMilli(1<<48 - 1), "7ZZZZZZZZZ"},
+	}
+
+	for _, tt := range tests {
+		id, err := NewULID(tt.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ulidPattern.MatchString(id) {
+			t.Fatalf("NewULID(%d) = %q, not a ULID", tt.time.UnixMilli(), id)
+		}
+		if !strings.HasPrefix(id, tt.prefix) {
+			t.Fatalf("NewULID(%d) = %q, want timestamp %s", tt.time.UnixMilli(), id, tt.prefix)
+		}
+	}
+
+	// Urut berdasarkan waktu
+	earlier, _ := NewULID(time.UnixMilli(1700000000000))
+	later, _ := NewULID(time.UnixMilli(1700000000001))
+	if earlier >= later {
+		t.Fatalf("ULID %s should sort before %s", earlier, later)
+	}
+}
+
+var uuidv7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUIDv7(t *testing.T) {
+	tests := []struct {
+		time   time.Time
+		prefix string
+	}{
+		{time.UnixMilli(0), "00000000-0000-7"},
+		// Contoh dari RFC 9562 appendix A.6
+		{time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC), "017f22e2-79b0-7"},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			id, err := NewUUIDv7(tt.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !uuidv7Pattern.MatchString(id) {
+				t.Fatalf("NewUUIDv7 = %q, not a version 7 UUID", id)
+			}
+			if !strings.HasPrefix(id, tt.prefix) {
+				t.Fatalf("NewUUIDv7 = %q, want prefix %s", id, tt.prefix)
+			}
+		}
+	}
+}
+
+func TestNamerName(t *testing.T) {
+	now := time.Date(2024, 3, 7, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		namer   Namer
+		vars    NameVars
+		pattern string
+		err     error
+	}{
+		{"default", Namer{Scheme: NameRandom, Length: 8, Template: DefaultNameTemplate}, NameVars{Ext: ".png", Time: now}, `^[a-zA-Z0-9]{8}\.png$`, nil},
+		{"dated tenant", Namer{Scheme: NameULID, Template: "{tenant}/{yyyy}/{mm}/{dd}/{id}{ext}"}, NameVars{Tenant: "acme", Ext: ".pdf", Time: now}, `^acme/2024/03/07/[0-9A-Z]{26}\.pdf$`, nil},
+		{"uuidv7", Namer{Scheme: NameUUIDv7, Template: "files/{id}"}, NameVars{Time: now}, `^files/[0-9a-f-]{36}$`, nil},
+		{"missing tenant", Namer{Scheme: NameULID, Template: "{tenant}/{id}"}, NameVars{Time: now}, "", ErrInvalidObjectPath},
+		{"tenant with slash", Namer{Scheme: NameULID, Template: "{tenant}/{id}"}, NameVars{Tenant: "a/b", Time: now}, "", ErrInvalidObjectPath},
+		{"tenant traversal", Namer{Scheme: NameULID, Template: "{tenant}/{id}"}, NameVars{Tenant: "..", Time: now}, "", ErrInvalidObjectPath},
+		{"missing id", Namer{Scheme: NameULID, Template: "{tenant}{ext}"}, NameVars{Tenant: "a", Time: now}, "", ErrInvalidNameTemplate},
+		{"unknown placeholder", Namer{Scheme: NameULID, Template: "{user}/{id}"}, NameVars{Time: now}, "", ErrInvalidNameTemplate},
+		{"unbalanced", Namer{Scheme: NameULID, Template: "{id"}, NameVars{Time: now}, "", ErrInvalidNameTemplate},
+		{"zero length", Namer{Scheme: NameRandom, Template: "{id}"}, NameVars{Time: now}, "", ErrInvalidNameTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := tt.namer.Name(tt.vars)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Name = %q, %v, want %v", name, err, tt.err)
+				}
+				return
+			}
+			if err != nil || !regexp.MustCompile(tt.pattern).MatchString(name) {
+				t.Fatalf("Name = %q, %v, want match %s", name, err, tt.pattern)
+			}
+		})
+	}
+}
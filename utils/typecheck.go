@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+)
+
+// MismatchPolicy menentukan apa yang dilakukan kalau tipe yang dideklarasikan
+// client (ekstensi nama file / Content-Type) beda dengan hasil sniffing
+type MismatchPolicy string
+
+const (
+	// Tolak upload dengan error terstruktur
+	PolicyReject MismatchPolicy = "reject"
+	// Simpan dengan tipe hasil sniffing, deklarasi client diabaikan
+	PolicyTrustSniffed MismatchPolicy = "trust_sniffed"
+	// Simpan ke prefix karantina, tidak bisa di-download sampai direview
+	PolicyQuarantine MismatchPolicy = "quarantine"
+)
+
+func ParseMismatchPolicy(s string) (MismatchPolicy, error) {
+	switch p := MismatchPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case PolicyReject, PolicyTrustSniffed, PolicyQuarantine:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown mismatch policy: %q", s)
+	}
+}
+
+// TypeMismatch berisi detail perbedaan deklarasi vs hasil sniffing, aman
+// untuk dikirim apa adanya ke client sebagai JSON
+type TypeMismatch struct {
+	Code                string   `json:"code"`
+	Message             string   `json:"message"`
+	DeclaredName        string   `json:"declared_name,omitempty"`
+	DeclaredExtension   string   `json:"declared_extension,omitempty"`
+	DeclaredContentType string   `json:"declared_content_type,omitempty"`
+	DetectedContentType string   `json:"detected_content_type"`
+	ExpectedExtensions  []string `json:"expected_extensions"`
+}
+
+func (m *TypeMismatch) Error() string {
+	return m.Message
+}
+
+// CheckDeclaredType membandingkan nama file dan Content-Type dari client
+// dengan tipe hasil DetectType. Deklarasi kosong atau
+// application/octet-stream dianggap tidak mendeklarasikan apa-apa.
+// Hasilnya nil kalau semuanya konsisten.
+//
+// Plain text hanya dikenali sebagai text/plain, jadi untuk hasil sniff itu
+// deklarasi format teks lain (text/csv, report.csv, data.json, README.md)
+// juga dianggap konsisten.
+func CheckDeclaredType(filename string, contentType string, detected FileType) *TypeMismatch {
+	var problems []string
+
+	ext := strings.ToLower(path.Ext(filename))
+	if ext != "" && !containsString(detected.Extensions, ext) && !(isText(detected) && textExtensions[ext]) {
+		problems = append(problems, fmt.Sprintf("extension %s", ext))
+	}
+
+	declaredType := ""
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			mediaType = contentType
+		}
+		declaredType = strings.ToLower(mediaType)
+	}
+	if declaredType != "" && declaredType != "application/octet-stream" && declaredType != detected.MimeType && !(isText(detected) && isTextContentType(declaredType)) {
+		problems = append(problems, fmt.Sprintf("content type %s", declaredType))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return &TypeMismatch{
+		Code:                "type_mismatch",
+		Message:             fmt.Sprintf("declared %s does not match detected %s", strings.Join(problems, " and "), detected.MimeType),
+		DeclaredName:        filename,
+		DeclaredExtension:   ext,
+		DeclaredContentType: declaredType,
+		DetectedContentType: detected.MimeType,
+		ExpectedExtensions:  detected.Extensions,
+	}
+}
+
+// Ekstensi format berbasis teks yang hasil sniff-nya text/plain
+var textExtensions = map[string]bool{
+	".txt": true, ".text": true, ".log": true, ".md": true, ".markdown": true,
+	".csv": true, ".tsv": true, ".json": true, ".ndjson": true, ".xml": true,
+	".yaml": true, ".yml": true, ".ini": true, ".conf": true,
+}
+
+// Content-Type teks di luar text/*
+var textContentTypes = map[string]bool{
+	"application/json":     true,
+	"application/x-ndjson": true,
+	"application/xml":      true,
+	"application/yaml":     true,
+	"application/x-yaml":   true,
+}
+
+func isText(t FileType) bool {
+	return t.MimeType == "text/plain"
+}
+
+func isTextContentType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") || textContentTypes[mediaType]
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
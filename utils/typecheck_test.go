@@ -0,0 +1,62 @@
+package utils
+
+import "testing"
+
+func TestCheckDeclaredType(t *testing.T) {
+	pdf, _ := LookupType("application/pdf")
+	jpeg, _ := LookupType("image/jpeg")
+	text, _ := LookupType("text/plain")
+
+	tests := []struct {
+		name        string
+		filename    string
+		contentType string
+		detected    FileType
+		mismatch    bool
+	}{
+		{"nothing declared", "", "", pdf, false},
+		{"octet-stream", "", "application/octet-stream", pdf, false},
+		{"matching", "doc.pdf", "application/pdf", pdf, false},
+		{"extension case", "DOC.PDF", "", pdf, false},
+		{"content type params", "", "application/pdf; charset=binary", pdf, false},
+		{"alternate extension", "photo.jpeg", "image/jpeg", jpeg, false},
+		{"wrong extension", "doc.png", "", pdf, true},
+		{"wrong content type", "doc.pdf", "image/png", pdf, true},
+		{"both wrong", "doc.png", "image/png", pdf, true},
+
+		{"csv", "report.csv", "text/csv", text, false},
+		{"json", "data.json", "application/json", text, false},
+		{"markdown", "README.md", "text/markdown", text, false},
+		{"yaml", "config.yml", "application/x-yaml", text, false},
+		{"text charset", "notes.txt", "text/plain; charset=utf-8", text, false},
+		{"text as pdf", "doc.pdf", "", text, true},
+		{"text as image", "", "image/png", text, true},
+		// Ekstensi teks tidak berlaku untuk hasil sniff selain text/plain
+		{"pdf as csv", "report.csv", "text/csv", pdf, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckDeclaredType(tt.filename, tt.contentType, tt.detected)
+			if (got != nil) != tt.mismatch {
+				t.Fatalf("CheckDeclaredType(%q, %q, %s) = %v, want mismatch %v", tt.filename, tt.contentType, tt.detected.MimeType, got, tt.mismatch)
+			}
+			if got != nil && (got.Code != "type_mismatch" || got.DetectedContentType != tt.detected.MimeType) {
+				t.Fatalf("unexpected mismatch detail %+v", got)
+			}
+		})
+	}
+}
+
+func TestParseMismatchPolicy(t *testing.T) {
+	for _, s := range []string{"reject", " Trust_Sniffed ", "QUARANTINE"} {
+		if _, err := ParseMismatchPolicy(s); err != nil {
+			t.Errorf("ParseMismatchPolicy(%q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "allow"} {
+		if _, err := ParseMismatchPolicy(s); err == nil {
+			t.Errorf("ParseMismatchPolicy(%q) should fail", s)
+		}
+	}
+}
@@ -1,19 +1,12 @@
 package utils
 
 import (
-	"encoding/base64"
 	"errors"
-	"math/rand"
+	"net/url"
 	"strings"
-	"time"
+	"unicode/utf8"
 )
 
-var signatures = map[string]string{
-	"JVBERi0":     "application/pdf",
-	"iVBORw0KGgo": "image/png",
-	"/9j/":        "image/jpeg",
-}
-
 type Base64File struct {
 	Name     string
 	Contents []byte
@@ -26,47 +19,123 @@ func NewBase64File(name string, contents []byte) *Base64File {
 	}
 }
 
-func detectMimeType(b64 string) (string, error) {
-	for s, mimeType := range signatures {
-		if strings.HasPrefix(b64, s) {
-			return mimeType, nil
-		}
+func DecodeBase64WithFormat(base64Data string) ([]byte, string, error) {
+	decodedData, t, _, err := DecodeBase64WithType(base64Data)
+	if err != nil {
+		return nil, "", err
 	}
-	return "", errors.New("unsupported base64 format")
+
+	return decodedData, t.Ext(), nil
 }
 
-func DecodeBase64WithFormat(base64Data string) ([]byte, string, error) {
-	mimeType, err := detectMimeType(base64Data)
+// DecodeBase64WithType decode base64 (atau data URI) lalu deteksi formatnya
+// dari magic byte. Mime type yang dideklarasikan data URI ikut dikembalikan.
+func DecodeBase64WithType(base64Data string) ([]byte, FileType, string, error) {
+	decodedData, declaredType, err := DecodeBase64(base64Data)
 	if err != nil {
-		return nil, "", err
+		return nil, FileType{}, "", err
 	}
 
-	decodedData, err := base64.StdEncoding.DecodeString(base64Data)
+	t, err := DetectType(decodedData)
 	if err != nil {
-		return nil, "", err
+		return nil, FileType{}, "", err
 	}
 
-	var ext string
-	switch mimeType {
-	case "application/pdf":
-		ext = ".pdf"
-	case "image/png":
-		ext = ".png"
-	case "image/jpeg":
-		ext = ".jpg"
-	default:
-		return nil, "", errors.New("unsupported mimeType: " + mimeType)
+	return decodedData, t, declaredType, nil
+}
+
+// DetectFormat mendeteksi mime type dan ekstensi dari byte awal sebuah file
+func DetectFormat(head []byte) (string, string, error) {
+	t, err := DetectType(head)
+	if err != nil {
+		return "", "", err
+	}
+
+	return t.MimeType, t.Ext(), nil
+}
+
+// ContentDisposition membentuk header Content-Disposition sesuai RFC 6266:
+// filename ASCII sebagai fallback plus filename* UTF-8 untuk browser modern.
+func ContentDisposition(dispositionType string, filename string) string {
+	fallback := make([]byte, 0, len(filename))
+	for _, r := range filename {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			fallback = append(fallback, '_')
+			continue
+		}
+		fallback = append(fallback, byte(r))
 	}
 
-	return decodedData, ext, nil
+	return dispositionType + `; filename="` + string(fallback) + `"; filename*=UTF-8''` + encodeRFC5987(filename)
 }
 
-func GenerateRandomName() string {
-	rand.Seed(time.Now().UnixNano())
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, 128)
-	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+// Percent-encode semua byte selain attr-char (RFC 5987)
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if isAttrChar(ch) {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0x0f])
+	}
+	return b.String()
+}
+
+func isAttrChar(ch byte) bool {
+	switch {
+	case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", ch) >= 0
+}
+
+var ErrInvalidObjectPath = errors.New("invalid object path")
+
+// Batas panjang nama object di GCS (byte UTF-8)
+const maxObjectPathLen = 1024
+
+// NormalizeObjectPath memvalidasi path object nested (mis. users/123/avatar.png)
+// dan mengembalikan bentuk kanoniknya tanpa slash di depan. Segmen "." dan "..",
+// segmen kosong, backslash, karakter kontrol, dan traversal yang di-encode
+// (%2e%2e, %252e%252e, ...) ditolak.
+func NormalizeObjectPath(p string) (string, error) {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || len(p) > maxObjectPathLen || !utf8.ValidString(p) {
+		return "", ErrInvalidObjectPath
+	}
+
+	// Cek juga hasil decode berulang supaya double encoding tidak lolos
+	candidate := p
+	for i := 0; i < 3; i++ {
+		if !validObjectPath(candidate) {
+			return "", ErrInvalidObjectPath
+		}
+		decoded, err := url.PathUnescape(candidate)
+		if err != nil || decoded == candidate {
+			break
+		}
+		candidate = decoded
+	}
+
+	return p, nil
+}
+
+func validObjectPath(p string) bool {
+	for _, r := range p {
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) || r == '\\' {
+			return false
+		}
+	}
+
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
 	}
-	return string(b)
+	return true
 }
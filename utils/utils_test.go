@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeObjectPath(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		ok   bool
+	}{
+		{"nested", "users/123/avatar.png", "users/123/avatar.png", true},
+		{"leading slash", "/users/123/avatar.png", "users/123/avatar.png", true},
+		{"encoded name", "users/a%20b.png", "users/a%20b.png", true},
+		{"unicode", "users/gambar ü.png", "users/gambar ü.png", true},
+		{"empty", "", "", false},
+		{"only slash", "/", "", false},
+		{"dot segment", "users/./a.png", "", false},
+		{"traversal", "users/../secret", "", false},
+		{"traversal at end", "users/..", "", false},
+		{"empty segment", "users//a.png", "", false},
+		{"trailing slash", "users/", "", false},
+		{"backslash", `users\..\secret`, "", false},
+		{"encoded traversal", "users/%2e%2e/secret", "", false},
+		{"encoded traversal upper", "users/%2E%2E/secret", "", false},
+		{"encoded slash traversal", "users/..%2fsecret", "", false},
+		{"double encoded traversal", "users/%252e%252e/secret", "", false},
+		{"encoded backslash", "users/%5c..", "", false},
+		{"newline", "users/a\nb.png", "", false},
+		{"nul", "users/a\x00.png", "", false},
+		{"del", "users/a\x7f.png", "", false},
+		{"c1 control", "users/a\u0085.png", "", false},
+		{"encoded control", "users/a%0a.png", "", false},
+		{"invalid utf8", "users/\xff.png", "", false},
+		{"too long", strings.Repeat("a", maxObjectPathLen+1), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeObjectPath(tt.in)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidObjectPath) {
+					t.Fatalf("NormalizeObjectPath(%q) = %q, %v, want ErrInvalidObjectPath", tt.in, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeObjectPath(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"firebase-poc/types"
+
+	cloudStorage "cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/iterator"
+)
+
+type objectVersion struct {
+	Generation     int64     `json:"generation"`
+	Metageneration int64     `json:"metageneration"`
+	Size           int64     `json:"size"`
+	ContentType    string    `json:"content_type"`
+	Updated        time.Time `json:"updated"`
+	// Waktu versi ini tergantikan/dihapus, kosong untuk versi live
+	Deleted *time.Time `json:"deleted,omitempty"`
+	Live    bool       `json:"live"`
+}
+
+// List semua generation dari satu object (bucket harus versioning enabled).
+// Versi lama bisa di-download lewat /url/*path?generation=N.
+func listObjectVersionsHandler(c *gin.Context, objectPath string) {
+	pageSize, err := pageSizeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Prefix match juga mengembalikan object lain yang namanya diawali path ini,
+	// jadi pakai range [path, path+"\x00") supaya hanya nama yang persis sama
+	query := &cloudStorage.Query{
+		Versions:    true,
+		StartOffset: objectPath,
+		EndOffset:   objectPath + "\x00",
+	}
+
+	var page []*cloudStorage.ObjectAttrs
+	nextPageToken, err := iterator.NewPager(bucket.Objects(c, query), pageSize, c.Query("pageToken")).NextPage(&page)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	versions := []objectVersion{}
+	for _, attrs := range page {
+		if attrs.Name != objectPath {
+			continue
+		}
+
+		version := objectVersion{
+			Generation:     attrs.Generation,
+			Metageneration: attrs.Metageneration,
+			Size:           attrs.Size,
+			ContentType:    attrs.ContentType,
+			Updated:        attrs.Updated,
+			Live:           attrs.Deleted.IsZero(),
+		}
+		if !attrs.Deleted.IsZero() {
+			deleted := attrs.Deleted
+			version.Deleted = &deleted
+		}
+		versions = append(versions, version)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":            objectPath,
+		"versions":        versions,
+		"next_page_token": nextPageToken,
+	})
+}
+
+// Restore generation lama dengan meng-copy-nya menimpa object live.
+// ifGenerationMatch di query berlaku untuk object live, jadi restore tidak
+// menimpa perubahan yang belum dilihat client.
+func restoreObjectVersionHandler(c *gin.Context, objectPath string) {
+	conds, err := objectConditions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req types.RestoreVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Generation <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "generation is required"})
+		return
+	}
+	if isBlobObject(objectPath) {
+		c.JSON(http.StatusConflict, gin.H{"error": errBlobManaged.Error()})
+		return
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	obj := bucket.Object(objectPath)
+	attrs, err := withConditions(obj, conds).CopierFrom(obj.Generation(req.Generation)).Run(c)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Thumbnail dan cache /img milik isi sebelumnya, jadi dibuang lalu
+	// thumbnail dibuat ulang dari generation yang di-restore
+	deleteDerivedObjects(c, bucket, objectPath)
+	if updated := regenerateThumbnails(c, bucket, attrs, thumbnailSizes("RESTORE")); updated != nil {
+		attrs = updated
+	}
+
+	c.JSON(http.StatusOK, objectMetaResponse(attrs))
+}